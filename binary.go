@@ -0,0 +1,109 @@
+package filescombine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// peekSize is how much of a file is sniffed up front to classify it as
+// binary or text, and to detect its encoding, before deciding how (or
+// whether) to read the rest.
+const peekSize = 8192
+
+// BinaryMode controls how a file classified as binary is represented in
+// Gather's results.
+type BinaryMode string
+
+const (
+	// BinaryModeSkip omits binary files from the results entirely.
+	BinaryModeSkip BinaryMode = "skip"
+	// BinaryModeSummary replaces a binary file's content with a short
+	// "<binary N bytes, sha256=...>" placeholder.
+	BinaryModeSummary BinaryMode = "summary"
+	// BinaryModeBase64 embeds the file's raw bytes as base64 text.
+	BinaryModeBase64 BinaryMode = "base64"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16leBOM = []byte{0xFF, 0xFE}
+	utf16beBOM = []byte{0xFE, 0xFF}
+)
+
+// classification is the result of sniffing a file's first peekSize bytes.
+type classification struct {
+	isBinary bool
+	encoding string // "utf-8", "utf-16le", or "utf-16be"
+}
+
+// classify sniffs peek (a prefix of a file, at most peekSize bytes) the way
+// readFile does: a UTF-16 BOM always wins (UTF-16 text is full of NUL
+// bytes for ASCII runs, so the NUL heuristic below would otherwise flag it
+// as binary), then a NUL byte or a non-"text/" http.DetectContentType
+// verdict marks the file binary.
+func classify(peek []byte) classification {
+	switch {
+	case bytes.HasPrefix(peek, utf16leBOM):
+		return classification{encoding: "utf-16le"}
+	case bytes.HasPrefix(peek, utf16beBOM):
+		return classification{encoding: "utf-16be"}
+	}
+
+	if bytes.IndexByte(peek, 0) != -1 {
+		return classification{isBinary: true}
+	}
+
+	if contentType := http.DetectContentType(peek); !strings.HasPrefix(contentType, "text/") {
+		return classification{isBinary: true}
+	}
+
+	return classification{encoding: "utf-8"}
+}
+
+// renderBinary renders a binary file's raw bytes per binaryMode: this is
+// the one place that decides what a binary file looks like in output, so
+// both a fresh read and a cache hit (which can only have kept the raw
+// bytes, since the rendering depends on this run's binaryMode rather than
+// the file itself) produce identical results for the same mode. hash is
+// the file's hex sha256, reused here rather than recomputed for
+// BinaryModeSummary.
+func renderBinary(data []byte, size int64, hash string, binaryMode BinaryMode) (result FileResult, skip bool) {
+	if binaryMode == "" {
+		binaryMode = BinaryModeSkip
+	}
+
+	switch binaryMode {
+	case BinaryModeSkip:
+		return FileResult{}, true
+	case BinaryModeBase64:
+		return FileResult{IsBinary: true, Size: size, Content: base64.StdEncoding.EncodeToString(data)}, false
+	default: // BinaryModeSummary
+		return FileResult{IsBinary: true, Size: size, Content: fmt.Sprintf("<binary %d bytes, sha256=%s>", size, hash)}, false
+	}
+}
+
+// normalizeToUTF8 strips a UTF-8 BOM or transcodes UTF-16 (as detected by
+// classify) to UTF-8, leaving already-UTF-8 content untouched.
+func normalizeToUTF8(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "utf-16le":
+		decoded, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("decoding utf-16le: %w", err)
+		}
+		return decoded, nil
+	case "utf-16be":
+		decoded, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("decoding utf-16be: %w", err)
+		}
+		return decoded, nil
+	default:
+		return bytes.TrimPrefix(data, utf8BOM), nil
+	}
+}
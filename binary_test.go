@@ -0,0 +1,195 @@
+package filescombine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestClassifyUTF16BOMWinsOverNULHeuristic(t *testing.T) {
+	// UTF-16 text is full of NUL bytes for ASCII runs, so the BOM check
+	// must be tried before the NUL-byte binary heuristic, not after.
+	encoded, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cls := classify(encoded)
+	if cls.isBinary {
+		t.Errorf("expected UTF-16LE text to classify as text, got isBinary=true")
+	}
+	if cls.encoding != "utf-16le" {
+		t.Errorf("encoding = %q, want utf-16le", cls.encoding)
+	}
+}
+
+func TestClassifyUTF16BEBOM(t *testing.T) {
+	encoded, err := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cls := classify(encoded)
+	if cls.isBinary {
+		t.Errorf("expected UTF-16BE text to classify as text, got isBinary=true")
+	}
+	if cls.encoding != "utf-16be" {
+		t.Errorf("encoding = %q, want utf-16be", cls.encoding)
+	}
+}
+
+func TestClassifyNULByteIsBinary(t *testing.T) {
+	cls := classify([]byte{'a', 'b', 0x00, 'c'})
+	if !cls.isBinary {
+		t.Error("expected a NUL byte to mark the content binary")
+	}
+}
+
+func TestClassifyNonTextContentType(t *testing.T) {
+	// a PNG header: no NUL in the first few bytes, but DetectContentType
+	// won't call it text/*.
+	cls := classify([]byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A})
+	if !cls.isBinary {
+		t.Error("expected PNG magic bytes to classify as binary")
+	}
+}
+
+func TestClassifyPlainUTF8Text(t *testing.T) {
+	cls := classify([]byte("hello, world\n"))
+	if cls.isBinary {
+		t.Error("expected plain text to classify as text")
+	}
+	if cls.encoding != "utf-8" {
+		t.Errorf("encoding = %q, want utf-8", cls.encoding)
+	}
+}
+
+func TestNormalizeToUTF8StripsUTF8BOM(t *testing.T) {
+	data := append(append([]byte{}, utf8BOM...), []byte("hello")...)
+
+	got, err := normalizeToUTF8(data, "utf-8")
+	if err != nil {
+		t.Fatalf("normalizeToUTF8: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want the BOM stripped", got)
+	}
+}
+
+func TestNormalizeToUTF8TranscodesUTF16LE(t *testing.T) {
+	encoded, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := normalizeToUTF8(encoded, "utf-16le")
+	if err != nil {
+		t.Fatalf("normalizeToUTF8: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want hello", got)
+	}
+}
+
+func TestNormalizeToUTF8TranscodesUTF16BE(t *testing.T) {
+	encoded, err := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := normalizeToUTF8(encoded, "utf-16be")
+	if err != nil {
+		t.Fatalf("normalizeToUTF8: %v", err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want hello", got)
+	}
+}
+
+func TestRenderBinaryModes(t *testing.T) {
+	data := []byte{0x00, 0x01, 0xff}
+
+	if _, skip := renderBinary(data, int64(len(data)), "abc", BinaryModeSkip); !skip {
+		t.Error("expected BinaryModeSkip to skip")
+	}
+
+	summary, skip := renderBinary(data, int64(len(data)), "abc", BinaryModeSummary)
+	if skip {
+		t.Error("expected BinaryModeSummary not to skip")
+	}
+	if summary.Content == "" || !summary.IsBinary {
+		t.Errorf("unexpected summary result: %+v", summary)
+	}
+
+	b64, skip := renderBinary(data, int64(len(data)), "abc", BinaryModeBase64)
+	if skip {
+		t.Error("expected BinaryModeBase64 not to skip")
+	}
+	if b64.Content == "" || !b64.IsBinary {
+		t.Errorf("unexpected base64 result: %+v", b64)
+	}
+
+	// an empty/unset mode behaves like BinaryModeSkip.
+	if _, skip := renderBinary(data, int64(len(data)), "abc", ""); !skip {
+		t.Error("expected an empty BinaryMode to default to skip")
+	}
+}
+
+func TestReadFileRejectsOverMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, skip, err := readFile(path, 5, BinaryModeSkip, nil)
+	if skip {
+		t.Error("a rejected file shouldn't also be marked skip")
+	}
+	if err == nil {
+		t.Fatal("expected an error for a file over MaxFileSize")
+	}
+}
+
+func TestReadFileSkipsBinaryWithoutReadingWholeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bin.dat")
+	data := append([]byte{0x00, 0x01, 0x02}, bytes.Repeat([]byte{0xff}, 100)...)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, skip, err := readFile(path, 0, BinaryModeSkip, nil)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	if !skip {
+		t.Fatal("expected a binary file under BinaryModeSkip to be skipped")
+	}
+	if result.Content != "" {
+		t.Errorf("expected a skipped file to carry no content, got %q", result.Content)
+	}
+}
+
+func TestReadFileRendersBinarySummary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bin.dat")
+	data := []byte{0x00, 0x01, 0x02, 0xff}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, skip, err := readFile(path, 0, BinaryModeSummary, nil)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	if skip {
+		t.Fatal("expected BinaryModeSummary not to skip")
+	}
+	if !result.IsBinary || result.Content == "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,217 @@
+package filescombine
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cacheEntry is the on-disk, content-addressed representation of a
+// processed file: keyed by the sha256 of its raw bytes, so two paths with
+// identical content (or the same path re-read across runs) share one
+// entry. TokenCounts is filled in lazily, keyed by tokenizer name, so a
+// later run with --token-budget can reuse a prior count instead of
+// re-tokenizing unchanged content.
+//
+// A binary file's rendering depends on the run's BinaryMode, not just its
+// bytes, so RawData (not a rendered Content) is what's cached for it;
+// renderBinary re-derives the mode-appropriate Content on every lookup.
+// Content is only populated, and meaningful, for non-binary entries.
+type cacheEntry struct {
+	Content     string
+	RawData     []byte
+	IsBinary    bool
+	Size        int64
+	Encoding    string
+	TokenCounts map[string]int
+}
+
+// cachePointer records which content hash a (path, mtime, size) triple
+// last resolved to, so a cache hit never needs to re-read or re-hash the
+// file to find its entry.
+type cachePointer struct {
+	ModTime int64
+	Size    int64
+	Sha256  string
+}
+
+// cache is an on-disk cache rooted at a directory under
+// $XDG_CACHE_HOME/files-combine (or ~/.cache/files-combine). A nil *cache
+// disables caching everywhere it's threaded through; every method on it
+// is a safe no-op in that case.
+type cache struct {
+	root string
+}
+
+// openCache resolves the cache root and returns a handle to it. It does
+// not touch disk: directories are created lazily on first store.
+func openCache() (*cache, error) {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("resolving cache directory: %w", err)
+		}
+		base = filepath.Join(home, ".cache")
+	}
+	return &cache{root: filepath.Join(base, "files-combine")}, nil
+}
+
+func (c *cache) pointerPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(c.root, "pointers", fmt.Sprintf("%x.json", sum)), nil
+}
+
+func (c *cache) objectPath(sha256Hex string) string {
+	return filepath.Join(c.root, "objects", sha256Hex[:2], sha256Hex+".json")
+}
+
+// lookup returns the cached result for path if a pointer exists and its
+// recorded mtime and size still match info, sparing the caller a re-read
+// of the file and a re-hash of its content. binaryMode is applied to a
+// cached binary entry's raw bytes fresh on every call, the same as a
+// cache miss would, since a file's rendering depends on this run's
+// BinaryMode and not just its content.
+func (c *cache) lookup(path string, info fs.FileInfo, binaryMode BinaryMode) (result FileResult, hash string, skip bool, hit bool) {
+	if c == nil {
+		return FileResult{}, "", false, false
+	}
+
+	ptrPath, err := c.pointerPath(path)
+	if err != nil {
+		return FileResult{}, "", false, false
+	}
+	data, err := os.ReadFile(ptrPath)
+	if err != nil {
+		return FileResult{}, "", false, false
+	}
+
+	var ptr cachePointer
+	if err := json.Unmarshal(data, &ptr); err != nil {
+		return FileResult{}, "", false, false
+	}
+	if ptr.ModTime != info.ModTime().UnixNano() || ptr.Size != info.Size() {
+		return FileResult{}, "", false, false
+	}
+
+	entry, ok := c.readObject(ptr.Sha256)
+	if !ok {
+		return FileResult{}, "", false, false
+	}
+
+	if entry.IsBinary {
+		result, skip := renderBinary(entry.RawData, entry.Size, ptr.Sha256, binaryMode)
+		return result, ptr.Sha256, skip, true
+	}
+
+	return FileResult{
+		Content:  entry.Content,
+		Size:     entry.Size,
+		Encoding: entry.Encoding,
+	}, ptr.Sha256, false, true
+}
+
+// cacheableFile is what readFile hands to store: the size/encoding that
+// never change for this content, and either the final transcoded Content
+// (non-binary, mode-independent) or the RawData needed to re-render a
+// binary file per whatever BinaryMode a later run asks for.
+type cacheableFile struct {
+	IsBinary bool
+	Size     int64
+	Encoding string
+	Content  string
+	RawData  []byte
+}
+
+// store records f under the content-addressed object for rawSha256 and
+// points path's (mtime, size) at it, so a future run of Gather over an
+// unchanged file skips reading and re-hashing it.
+func (c *cache) store(path string, info fs.FileInfo, rawSha256 string, f cacheableFile) {
+	if c == nil {
+		return
+	}
+
+	entry := cacheEntry{
+		Content:  f.Content,
+		RawData:  f.RawData,
+		IsBinary: f.IsBinary,
+		Size:     f.Size,
+		Encoding: f.Encoding,
+	}
+	if err := c.writeObject(rawSha256, entry); err != nil {
+		return
+	}
+
+	ptr := cachePointer{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Sha256: rawSha256}
+	ptrPath, err := c.pointerPath(path)
+	if err != nil {
+		return
+	}
+	writeJSONFile(ptrPath, ptr)
+}
+
+// tokenCount returns a previously cached token count for rawSha256 under
+// tokenizer, if one has been stored, so packToBudget can plan a run
+// without re-tokenizing content it already measured on a prior run.
+func (c *cache) tokenCount(rawSha256, tokenizer string) (int, bool) {
+	if c == nil {
+		return 0, false
+	}
+	entry, ok := c.readObject(rawSha256)
+	if !ok {
+		return 0, false
+	}
+	count, ok := entry.TokenCounts[tokenizer]
+	return count, ok
+}
+
+// storeTokenCount records count for rawSha256 under tokenizer, merging it
+// into whatever object already exists for that content.
+func (c *cache) storeTokenCount(rawSha256, tokenizer string, count int) {
+	if c == nil {
+		return
+	}
+	entry, ok := c.readObject(rawSha256)
+	if !ok {
+		return
+	}
+	if entry.TokenCounts == nil {
+		entry.TokenCounts = make(map[string]int)
+	}
+	entry.TokenCounts[tokenizer] = count
+	c.writeObject(rawSha256, entry)
+}
+
+func (c *cache) readObject(sha256Hex string) (cacheEntry, bool) {
+	data, err := os.ReadFile(c.objectPath(sha256Hex))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *cache) writeObject(sha256Hex string, entry cacheEntry) error {
+	return writeJSONFile(c.objectPath(sha256Hex), entry)
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
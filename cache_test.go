@@ -0,0 +1,189 @@
+package filescombine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T) *cache {
+	t.Helper()
+	return &cache{root: t.TempDir()}
+}
+
+func statFile(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestCacheLookupMissWithoutStore(t *testing.T) {
+	c := newTestCache(t)
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("hi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, _, hit := c.lookup(path, statFile(t, path), BinaryModeSkip)
+	if hit {
+		t.Error("expected a miss for a path never stored")
+	}
+}
+
+func TestCacheHitAfterStore(t *testing.T) {
+	c := newTestCache(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info := statFile(t, path)
+
+	c.store(path, info, "deadbeef", cacheableFile{Size: info.Size(), Encoding: "utf-8", Content: "hello"})
+
+	result, hash, skip, hit := c.lookup(path, info, BinaryModeSkip)
+	if !hit {
+		t.Fatal("expected a cache hit")
+	}
+	if skip {
+		t.Error("a non-binary entry should never be marked skip")
+	}
+	if hash != "deadbeef" {
+		t.Errorf("hash = %q, want deadbeef", hash)
+	}
+	if result.Content != "hello" {
+		t.Errorf("Content = %q, want hello", result.Content)
+	}
+}
+
+func TestCacheInvalidatesOnSizeChange(t *testing.T) {
+	c := newTestCache(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info := statFile(t, path)
+	c.store(path, info, "deadbeef", cacheableFile{Size: info.Size(), Content: "hello"})
+
+	if err := os.WriteFile(path, []byte("hello world, now longer"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	newInfo := statFile(t, path)
+
+	_, _, _, hit := c.lookup(path, newInfo, BinaryModeSkip)
+	if hit {
+		t.Error("expected a miss once the file's size no longer matches the stored pointer")
+	}
+}
+
+func TestCacheInvalidatesOnModTimeChange(t *testing.T) {
+	c := newTestCache(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info := statFile(t, path)
+	c.store(path, info, "deadbeef", cacheableFile{Size: info.Size(), Content: "hello"})
+
+	// same size and content, but a different mtime -- the pointer's
+	// (mtime, size) check should still invalidate it.
+	later := info.ModTime().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	newInfo := statFile(t, path)
+
+	_, _, _, hit := c.lookup(path, newInfo, BinaryModeSkip)
+	if hit {
+		t.Error("expected a miss once the file's mtime no longer matches the stored pointer")
+	}
+}
+
+func TestCacheBinaryEntryRerendersPerBinaryMode(t *testing.T) {
+	// the bug the review caught: a binary file cached under one
+	// --binary-mode must still render correctly when looked up again
+	// under a different --binary-mode, without touching the file.
+	c := newTestCache(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.bin")
+	raw := []byte{0x00, 0x01, 0x02, 0xff}
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info := statFile(t, path)
+
+	c.store(path, info, "abc123", cacheableFile{IsBinary: true, Size: info.Size(), RawData: raw})
+
+	summary, _, skip, hit := c.lookup(path, info, BinaryModeSummary)
+	if !hit || skip {
+		t.Fatalf("expected a hit under summary mode, got hit=%v skip=%v", hit, skip)
+	}
+	if summary.Content == "" {
+		t.Error("expected non-empty summary content")
+	}
+
+	b64, _, skip, hit := c.lookup(path, info, BinaryModeBase64)
+	if !hit || skip {
+		t.Fatalf("expected a hit under base64 mode, got hit=%v skip=%v", hit, skip)
+	}
+	if b64.Content == summary.Content {
+		t.Error("base64 and summary renderings of the same cached entry must differ")
+	}
+
+	_, _, skip, hit = c.lookup(path, info, BinaryModeSkip)
+	if !hit {
+		t.Fatal("expected a hit under skip mode")
+	}
+	if !skip {
+		t.Error("skip mode should report skip=true even for a cache hit")
+	}
+}
+
+func TestCacheTokenCountRoundTrip(t *testing.T) {
+	c := newTestCache(t)
+	c.store("/irrelevant/path/never/statted", fakeFileInfo{size: 4}, "hash1", cacheableFile{Size: 4, Content: "abcd"})
+
+	if _, ok := c.tokenCount("hash1", "cl100k"); ok {
+		t.Fatal("expected no token count before storeTokenCount")
+	}
+
+	c.storeTokenCount("hash1", "cl100k", 7)
+
+	count, ok := c.tokenCount("hash1", "cl100k")
+	if !ok || count != 7 {
+		t.Fatalf("tokenCount = (%d, %v), want (7, true)", count, ok)
+	}
+}
+
+func TestNilCacheIsANoOp(t *testing.T) {
+	var c *cache
+
+	_, _, _, hit := c.lookup("anything", fakeFileInfo{}, BinaryModeSkip)
+	if hit {
+		t.Error("a nil cache should never report a hit")
+	}
+
+	c.store("anything", fakeFileInfo{}, "hash", cacheableFile{})
+	if _, ok := c.tokenCount("hash", "cl100k"); ok {
+		t.Error("a nil cache should never report a token count")
+	}
+}
+
+// fakeFileInfo is a minimal os.FileInfo for tests that don't need a real
+// file on disk, just a (size, mtime) pair to store or compare against.
+type fakeFileInfo struct {
+	size int64
+}
+
+func (f fakeFileInfo) Name() string       { return "fake" }
+func (f fakeFileInfo) Size() int64        { return f.size }
+func (f fakeFileInfo) Mode() os.FileMode  { return 0 }
+func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeFileInfo) IsDir() bool        { return false }
+func (f fakeFileInfo) Sys() interface{}   { return nil }
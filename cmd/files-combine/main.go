@@ -15,10 +15,19 @@ var (
 	ignoreFilesOnly bool
 	ignoreGitignore bool
 	ignorePatterns  []string
+	ignoreFiles     []string
+	respectGlobal   bool
 	outputFile      string
 	format          string
 	lineNumbers     bool
 	dryRun          bool
+	tokenBudget     int
+	tokenizer       string
+	packOrder       string
+	truncation      string
+	maxFileSize     int64
+	binaryMode      string
+	useCache        bool
 )
 
 var rootCmd = &cobra.Command{
@@ -58,13 +67,7 @@ var rootCmd = &cobra.Command{
 		log.Printf("Dry run: %v", dryRun)
 
 		// build gather opts
-		gatherOpts := filescombine.GatherOptions{
-			Extensions:      extensions,
-			IncludeHidden:   includeHidden,
-			IgnoreGitignore: ignoreGitignore,
-			IgnorePatterns:  ignorePatterns,
-			IgnoreFilesOnly: ignoreFilesOnly,
-		}
+		gatherOpts := gatherOptsFromFlags()
 
 		// gather files
 		results, err := filescombine.Gather(path, gatherOpts)
@@ -89,6 +92,11 @@ var rootCmd = &cobra.Command{
 			OutputFile:  outputFile,
 			Format:      format,
 			LineNumbers: lineNumbers,
+			TokenBudget: tokenBudget,
+			Tokenizer:   tokenizer,
+			Order:       packOrder,
+			Truncation:  filescombine.TruncationStrategy(truncation),
+			Cache:       useCache,
 		}
 
 		if err := filescombine.Combine(results, combineOpts); err != nil {
@@ -103,15 +111,45 @@ const version = "1.0.0"
 
 func init() {
 	rootCmd.Version = version
-	rootCmd.Flags().StringSliceVar(&extensions, "ext", []string{}, "File extensions to include")
-	rootCmd.Flags().BoolVar(&includeHidden, "include-hidden", true, "Include files starting with . (default: true)")
-	rootCmd.Flags().BoolVar(&ignoreFilesOnly, "ignore-files-only", false, "--ignore only applies to files")
-	rootCmd.Flags().BoolVar(&ignoreGitignore, "ignore-gitignore", false, "Ignore .gitignore files")
-	rootCmd.Flags().StringSliceVar(&ignorePatterns, "ignore", []string{}, "Patterns to ignore")
+
+	// gather flags, shared with subcommands (e.g. manifest)
+	rootCmd.PersistentFlags().StringSliceVar(&extensions, "ext", []string{}, "File extensions to include")
+	rootCmd.PersistentFlags().BoolVar(&includeHidden, "include-hidden", true, "Include files starting with . (default: true)")
+	rootCmd.PersistentFlags().BoolVar(&ignoreFilesOnly, "ignore-files-only", false, "--ignore only applies to files")
+	rootCmd.PersistentFlags().BoolVar(&ignoreGitignore, "ignore-gitignore", false, "Ignore .gitignore files")
+	rootCmd.PersistentFlags().StringSliceVar(&ignorePatterns, "ignore", []string{}, "Patterns to ignore")
+	rootCmd.PersistentFlags().StringArrayVar(&ignoreFiles, "ignore-file", []string{}, "Pathspec rule file to ignore by (supports #include, repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&respectGlobal, "respect-global-gitignore", false, "Honor core.excludesFile and the XDG global gitignore")
+	rootCmd.PersistentFlags().Int64Var(&maxFileSize, "max-file-size", 0, "Reject files larger than this many bytes (0: no limit)")
+	rootCmd.PersistentFlags().StringVar(&binaryMode, "binary-mode", "skip", "How to handle binary files: skip, summary, base64")
+	rootCmd.PersistentFlags().BoolVar(&useCache, "cache", false, "Cache file contents and token counts under $XDG_CACHE_HOME/files-combine across runs")
+
+	// combine-only flags
 	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file (default: output.md or output.xml based on format)")
 	rootCmd.Flags().StringVarP(&format, "format", "f", "markdown", "Output format: 'xml' or 'markdown' (default: markdown)")
 	rootCmd.Flags().BoolVarP(&lineNumbers, "line-numbers", "n", false, "Add line numbers")
 	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print files that will be combined without processing")
+	rootCmd.Flags().IntVar(&tokenBudget, "token-budget", 0, "Cap combined output to roughly this many tokens (0: no cap)")
+	rootCmd.Flags().StringVar(&tokenizer, "tokenizer", "bytes/4", "Token estimator: cl100k, o200k, or bytes/4")
+	rootCmd.Flags().StringVar(&packOrder, "order", "path", "Packing order when --token-budget is set: size-asc, size-desc, path (gitignore-priority is an alias of path)")
+	rootCmd.Flags().StringVar(&truncation, "truncation", "head", "How to shrink a file that overflows the budget: head, tail, head+tail, symbols-only")
+
+	rootCmd.AddCommand(manifestCmd)
+}
+
+func gatherOptsFromFlags() filescombine.GatherOptions {
+	return filescombine.GatherOptions{
+		Extensions:             extensions,
+		IncludeHidden:          includeHidden,
+		IgnoreGitignore:        ignoreGitignore,
+		IgnorePatterns:         ignorePatterns,
+		IgnoreFiles:            ignoreFiles,
+		IgnoreFilesOnly:        ignoreFilesOnly,
+		RespectGlobalGitignore: respectGlobal,
+		MaxFileSize:            maxFileSize,
+		BinaryMode:             filescombine.BinaryMode(binaryMode),
+		Cache:                  useCache,
+	}
 }
 
 func main() {
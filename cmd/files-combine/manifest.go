@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	filescombine "github.com/bnmoch3/files-combine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	manifestAlgo   string
+	manifestFormat string
+	manifestOutput string
+	verifyAgainst  string
+)
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest [path]",
+	Short: "Fingerprint a directory tree into a deterministic manifest",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hasher, err := filescombine.HasherByName(manifestAlgo)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		format := filescombine.ManifestFormat(manifestFormat)
+
+		var path string
+		if len(args) == 0 {
+			cwd, err := os.Getwd()
+			if err != nil {
+				log.Fatal(err)
+			}
+			path = cwd
+		} else {
+			path = args[0]
+		}
+
+		gatherOpts := gatherOptsFromFlags()
+
+		if verifyAgainst != "" {
+			runVerify(path, gatherOpts, hasher, format)
+			return
+		}
+
+		entries, err := filescombine.BuildManifest(path, gatherOpts, hasher)
+		if err != nil {
+			log.Fatalf("Error building manifest: %v", err)
+		}
+
+		out := os.Stdout
+		if manifestOutput != "" {
+			f, err := os.Create(manifestOutput)
+			if err != nil {
+				log.Fatalf("Error creating manifest file: %v", err)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if err := filescombine.WriteManifest(out, entries, format, hasher); err != nil {
+			log.Fatalf("Error writing manifest: %v", err)
+		}
+	},
+}
+
+func runVerify(path string, gatherOpts filescombine.GatherOptions, hasher filescombine.Hasher, format filescombine.ManifestFormat) {
+	manifestFile, err := os.Open(verifyAgainst)
+	if err != nil {
+		log.Fatalf("Error opening manifest: %v", err)
+	}
+	defer manifestFile.Close()
+
+	recorded, err := filescombine.ReadManifest(manifestFile, format)
+	if err != nil {
+		log.Fatalf("Error parsing manifest: %v", err)
+	}
+
+	report, err := filescombine.VerifyManifest(path, gatherOpts, hasher, recorded)
+	if err != nil {
+		log.Fatalf("Error verifying manifest: %v", err)
+	}
+
+	for _, p := range report.Added {
+		fmt.Printf("added: %s\n", p)
+	}
+	for _, p := range report.Deleted {
+		fmt.Printf("deleted: %s\n", p)
+	}
+	for _, p := range report.Modified {
+		fmt.Printf("modified: %s\n", p)
+	}
+
+	if !report.Clean() {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	manifestCmd.Flags().StringVar(&manifestAlgo, "algo", "sha256", "Hash algorithm: md5, sha1, sha256, blake3")
+	manifestCmd.Flags().StringVar(&manifestFormat, "manifest-format", "plain", "Manifest format: plain, jsonl, sri")
+	manifestCmd.Flags().StringVarP(&manifestOutput, "output", "o", "", "Write manifest to this file instead of stdout")
+	manifestCmd.Flags().StringVar(&verifyAgainst, "verify", "", "Verify the tree against a previously generated manifest instead of generating one")
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"crypto/md5"
+	"flag"
 	"fmt"
 	"io"
 	"io/fs"
@@ -10,6 +10,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"sync"
+
+	filescombine "github.com/bnmoch3/files-combine"
 )
 
 // FileInput input for downstream processing
@@ -97,29 +99,29 @@ func merge(done <-chan struct{}, channels ...<-chan FileResult) <-chan FileResul
 	return out
 }
 
-func calculateMD5(path string) (string, error) {
+func calculateHash(hasher filescombine.Hasher, path string) (string, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	h := hasher.New()
+	if _, err := io.Copy(h, file); err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
-func processFile(done <-chan struct{}, in <-chan FileInput) <-chan FileResult {
+func processFile(done <-chan struct{}, in <-chan FileInput, hasher filescombine.Hasher) <-chan FileResult {
 	out := make(chan FileResult)
 
 	go func() {
 		defer close(out)
 
 		for input := range in {
-			hash, err := calculateMD5(input.Path)
+			hash, err := calculateHash(hasher, input.Path)
 
 			result := FileResult{
 				Path:    input.Path,
@@ -140,6 +142,14 @@ func processFile(done <-chan struct{}, in <-chan FileInput) <-chan FileResult {
 }
 
 func main() {
+	algo := flag.String("algo", "md5", "hash algorithm: md5, sha1, sha256, blake3")
+	flag.Parse()
+
+	hasher, err := filescombine.HasherByName(*algo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	done := make(chan struct{})
 	defer close(done)
 
@@ -152,7 +162,7 @@ func main() {
 	numWorkers := runtime.NumCPU()
 	workerChs := make([]<-chan FileResult, numWorkers)
 	for i := 0; i < numWorkers; i++ {
-		workerChs[i] = processFile(done, filesCh)
+		workerChs[i] = processFile(done, filesCh, hasher)
 	}
 
 	// stage 3: merge & consume results
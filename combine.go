@@ -5,12 +5,37 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/bnmoch3/files-combine/tokens"
 )
 
 type CombineOpts struct {
 	OutputFile  string
 	Format      string // "markdown" or "xml"
 	LineNumbers bool
+
+	// TokenBudget, if > 0, caps the combined output to roughly this many
+	// tokens (per Tokenizer), packing files in Order and truncating
+	// (per Truncation) whichever file first overflows instead of just
+	// stopping. A summary of what was included/truncated/omitted is
+	// written at the top of the output.
+	TokenBudget int
+	Tokenizer   string             // "cl100k", "o200k", "bytes/4" (default: "bytes/4")
+	Order       string             // "size-asc", "size-desc", "path" (default); "gitignore-priority" is an alias of "path"
+	Truncation  TruncationStrategy // default: TruncateHead
+
+	// Cache, if true, reuses a file's token count from a prior run (keyed
+	// by FileResult.ContentHash) instead of re-tokenizing unchanged
+	// content. Shares the on-disk cache under $XDG_CACHE_HOME/files-combine.
+	Cache bool
+}
+
+// budgetEntry records how one file fared against a TokenBudget, for the
+// summary section written at the top of budget-packed output.
+type budgetEntry struct {
+	RelPath    string
+	TokenCount int
+	Status     string // "included", "truncated", or "omitted"
 }
 
 var extToLang = map[string]string{
@@ -41,10 +66,95 @@ func Combine(results []FileResult, opts CombineOpts) error {
 	}
 	defer file.Close()
 
+	body, summary, err := applyTokenBudget(results, opts)
+	if err != nil {
+		return err
+	}
+
 	if opts.Format == "xml" {
-		return combineAsXML(file, results, opts.LineNumbers)
+		writeSummaryXML(file, summary)
+		return combineAsXML(file, body, opts.LineNumbers)
 	}
-	return combineAsMarkdown(file, results, opts.LineNumbers)
+	writeSummaryMarkdown(file, summary)
+	return combineAsMarkdown(file, body, opts.LineNumbers)
+}
+
+// applyTokenBudget packs results down to opts.TokenBudget when one is set,
+// returning the files to actually render plus a summary of what happened
+// to each. With no budget configured, it's a passthrough.
+func applyTokenBudget(results []FileResult, opts CombineOpts) ([]FileResult, []budgetEntry, error) {
+	if opts.TokenBudget <= 0 {
+		return results, nil, nil
+	}
+
+	tokenizerName := opts.Tokenizer
+	if tokenizerName == "" {
+		tokenizerName = "bytes/4"
+	}
+	tok, err := tokens.ByName(tokenizerName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	strategy := opts.Truncation
+	if strategy == "" {
+		strategy = TruncateHead
+	}
+
+	var c *cache
+	if opts.Cache {
+		c, err = openCache()
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening cache: %w", err)
+		}
+	}
+
+	packed := packToBudget(results, opts.Order, opts.TokenBudget, strategy, tok, tokenizerName, c)
+
+	body := make([]FileResult, 0, len(packed))
+	summary := make([]budgetEntry, 0, len(packed))
+
+	for _, p := range packed {
+		status := "included"
+		switch {
+		case p.Omitted:
+			status = "omitted"
+		case p.Truncated:
+			status = "truncated"
+		}
+		summary = append(summary, budgetEntry{RelPath: p.RelPath, TokenCount: p.TokenCount, Status: status})
+
+		if !p.Omitted {
+			body = append(body, p.FileResult)
+		}
+	}
+
+	return body, summary, nil
+}
+
+func writeSummaryMarkdown(file *os.File, summary []budgetEntry) {
+	if len(summary) == 0 {
+		return
+	}
+
+	fmt.Fprintln(file, "# Token budget summary")
+	fmt.Fprintln(file)
+	for _, e := range summary {
+		fmt.Fprintf(file, "- %s: %s (%d tokens)\n", e.Status, e.RelPath, e.TokenCount)
+	}
+	fmt.Fprintln(file)
+}
+
+func writeSummaryXML(file *os.File, summary []budgetEntry) {
+	if len(summary) == 0 {
+		return
+	}
+
+	fmt.Fprintln(file, "<budget_summary>")
+	for _, e := range summary {
+		fmt.Fprintf(file, "<file path=%q status=%q tokens=\"%d\"/>\n", e.RelPath, e.Status, e.TokenCount)
+	}
+	fmt.Fprintln(file, "</budget_summary>")
 }
 
 func combineAsMarkdown(file *os.File, results []FileResult, lineNumbers bool) error {
@@ -69,7 +179,11 @@ func combineAsMarkdown(file *os.File, results []FileResult, lineNumbers bool) er
 		}
 
 		// Write markdown format
-		fmt.Fprintf(file, "%s\n", result.RelPath)
+		if result.IsBinary {
+			fmt.Fprintf(file, "%s (binary, %d bytes)\n", result.RelPath, result.Size)
+		} else {
+			fmt.Fprintf(file, "%s\n", result.RelPath)
+		}
 		fmt.Fprintf(file, "%s%s\n", backticks, lang)
 		fmt.Fprintf(file, "%s\n", content)
 		fmt.Fprintf(file, "%s\n", backticks)
@@ -92,7 +206,11 @@ func combineAsXML(file *os.File, results []FileResult, lineNumbers bool) error {
 			content = addLineNumbers(content)
 		}
 
-		fmt.Fprintf(file, "<document index=\"%d\">\n", index)
+		if result.IsBinary {
+			fmt.Fprintf(file, "<document index=\"%d\" binary=\"true\" size=\"%d\">\n", index, result.Size)
+		} else {
+			fmt.Fprintf(file, "<document index=\"%d\">\n", index)
+		}
 		fmt.Fprintf(file, "<source>%s</source>\n", result.RelPath)
 		fmt.Fprintln(file, "<document_content>")
 		fmt.Fprintln(file, content)
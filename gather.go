@@ -2,25 +2,43 @@ package filescombine
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 
-	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/bnmoch3/files-combine/pathspec"
 )
 
 // GatherOptions configuration for file gathering
 type GatherOptions struct {
-	Extensions      []string
-	IncludeHidden   bool
-	IgnoreGitignore bool
-	IgnorePatterns  []string
-	IgnoreFilesOnly bool
+	Extensions             []string
+	IncludeHidden          bool
+	IgnoreGitignore        bool
+	IgnorePatterns         []string
+	IgnoreFiles            []string
+	IgnoreFilesOnly        bool
+	RespectGlobalGitignore bool
+
+	// MaxFileSize, if > 0, rejects (with FileResult.Err set) any file
+	// larger than this many bytes instead of reading it into memory.
+	MaxFileSize int64
+	// BinaryMode controls how files classified as binary are represented.
+	// Defaults to BinaryModeSkip.
+	BinaryMode BinaryMode
+
+	// Cache, if true, reads and writes the on-disk cache under
+	// $XDG_CACHE_HOME/files-combine, so a file whose (path, mtime, size)
+	// hasn't changed since the last run is reused instead of re-read.
+	Cache bool
 }
 
 // FileInput input for downstream processing
@@ -34,7 +52,14 @@ type FileResult struct {
 	Path    string
 	RelPath string
 	Content string
-	Err     error
+	// ContentHash is the hex sha256 of the file's raw bytes, computed
+	// whether or not caching is on; it's the key the cache and the
+	// token-budget packer use to recognize unchanged content.
+	ContentHash string
+	IsBinary    bool
+	Size        int64
+	Encoding    string
+	Err         error
 }
 
 func walkFiles(done <-chan struct{}, dirPath string, opts GatherOptions) (<-chan FileInput, <-chan error) {
@@ -45,33 +70,60 @@ func walkFiles(done <-chan struct{}, dirPath string, opts GatherOptions) (<-chan
 		defer close(out)
 		defer close(errCh)
 
-		// load .gitignore patterns
-		var matcher gitignore.Matcher
-		if !opts.IgnoreGitignore {
-			patterns, err := loadGitignorePatterns(dirPath)
+		// baseRules holds every filter source except --ignore/--ignore-file,
+		// lowest-priority first: the --ext allow-list, hidden-file
+		// exclusion, global gitignore, then nested .gitignore rules as
+		// they're discovered descending the tree (so a deeper .gitignore's
+		// rules are appended after, and override, a shallower one's).
+		// userRules (--ignore/--ignore-file) is computed once up front and
+		// always kept last, so a user's explicit CLI rule has the final
+		// word over whatever any .gitignore says. chain is rebuilt from
+		// both whenever baseRules grows.
+		var baseRules []pathspec.Rule
+
+		if len(opts.Extensions) > 0 {
+			baseRules = append(baseRules, pathspec.ExtensionRule(opts.Extensions, "--ext"))
+		}
+		if !opts.IncludeHidden {
+			baseRules = append(baseRules, pathspec.HiddenRule("hidden file"))
+		}
+		if !opts.IgnoreGitignore && opts.RespectGlobalGitignore {
+			globalLines, err := loadGlobalGitignoreLines()
 			if err != nil {
-				errCh <- fmt.Errorf("loading gitignore: %w", err)
+				errCh <- fmt.Errorf("loading global gitignore: %w", err)
 				return
 			}
-			if len(patterns) > 0 {
-				matcher = gitignore.NewMatcher(patterns)
+			baseRules = append(baseRules, pathspec.FromGitignoreLines(globalLines, nil, "global gitignore")...)
+		}
+
+		userRules := pathspec.CompileIgnorePatterns(opts.IgnorePatterns, opts.IgnoreFilesOnly, "--ignore")
+		for _, ruleFile := range opts.IgnoreFiles {
+			fileRules, err := pathspec.LoadRulesFile(ruleFile)
+			if err != nil {
+				errCh <- fmt.Errorf("loading ignore file %s: %w", ruleFile, err)
+				return
 			}
+			userRules = append(userRules, fileRules...)
+		}
+
+		var chain *pathspec.Chain
+		rebuildChain := func() {
+			rules := make([]pathspec.Rule, 0, len(baseRules)+len(userRules))
+			rules = append(rules, baseRules...)
+			rules = append(rules, userRules...)
+			chain = pathspec.New(rules)
 		}
+		rebuildChain()
 
 		err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
 
-			// skip hidden files/dirs if not included
-			if !opts.IncludeHidden && strings.HasPrefix(d.Name(), ".") {
-				if d.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-
-			// skip .git and other VCS directories
+			// .git and other VCS directories are always skipped,
+			// regardless of any ignore rule: unlike the filters folded
+			// into chain below, this isn't something --include-hidden or
+			// a --ignore re-include should be able to override.
 			if d.IsDir() {
 				name := d.Name()
 				if name == ".git" || name == ".svn" || name == ".hg" {
@@ -88,24 +140,33 @@ func walkFiles(done <-chan struct{}, dirPath string, opts GatherOptions) (<-chan
 			// convert to forward slashes for gitignore matching
 			relPath = filepath.ToSlash(relPath)
 
-			// check gitignore (skip root)
-			if matcher != nil && relPath != "." {
-				if shouldIgnore(matcher, relPath, d.IsDir()) {
-					if d.IsDir() {
-						return filepath.SkipDir
-					}
-					return nil
+			// on entering a directory, fold in its own .gitignore (scoped
+			// to this directory's path) before filtering anything beneath it
+			if d.IsDir() && !opts.IgnoreGitignore {
+				var domain []string
+				if relPath != "." {
+					domain = strings.Split(relPath, "/")
+				}
+				dirLines, err := loadGitignoreLines(path)
+				if err != nil {
+					return fmt.Errorf("loading gitignore in %s: %w", relPath, err)
+				}
+				if len(dirLines) > 0 {
+					baseRules = append(baseRules, pathspec.FromGitignoreLines(dirLines, domain, relPath+"/.gitignore")...)
+					rebuildChain()
 				}
 			}
 
-			// check custom ignore patterns
-			if len(opts.IgnorePatterns) > 0 {
-				if shouldIgnorePatterns(d.Name(), d.IsDir(), opts.IgnorePatterns, opts.IgnoreFilesOnly) {
-					if d.IsDir() {
-						return filepath.SkipDir
-					}
-					return nil
+			// evaluate every filter — --ext, hidden files, global and
+			// nested gitignore, --ignore/--ignore-file — as one ordered,
+			// last-match-wins chain, so a rule from any source can
+			// override one from any other (e.g. a --ignore re-include
+			// pulling a path back in despite a .gitignore exclude).
+			if relPath != "." && chain.Match(strings.Split(relPath, "/"), d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
 				}
+				return nil
 			}
 
 			// skip directories and non-regular files
@@ -121,28 +182,6 @@ func walkFiles(done <-chan struct{}, dirPath string, opts GatherOptions) (<-chan
 				return nil
 			}
 
-			// filter by extensions if provided
-			if len(opts.Extensions) > 0 {
-				matched := false
-				fileExt := filepath.Ext(d.Name()) // e.g., ".go", ".mod", ".sum"
-
-				for _, ext := range opts.Extensions {
-					// add dot if not present
-					wantedExt := ext
-					if !strings.HasPrefix(wantedExt, ".") {
-						wantedExt = "." + wantedExt
-					}
-
-					if fileExt == wantedExt {
-						matched = true
-						break
-					}
-				}
-				if !matched {
-					return nil
-				}
-			}
-
 			select {
 			case out <- FileInput{Path: path, RelPath: relPath}:
 			case <-done:
@@ -155,168 +194,253 @@ func walkFiles(done <-chan struct{}, dirPath string, opts GatherOptions) (<-chan
 			errCh <- err
 		}
 	}()
-
 	return out, errCh
 }
 
-func shouldIgnorePatterns(name string, isDir bool, patterns []string, filesOnly bool) bool {
-	// if filesOnly is true and this is a directory, don't ignore
-	if filesOnly && isDir {
-		return false
+// enumerate walks dirPath with opts and returns the matched files sorted
+// by RelPath. Sorting here, rather than relying on filepath.WalkDir's own
+// lexical traversal, is what makes Gather's output order depend only on
+// the tree's contents, not on how it happened to be walked.
+func enumerate(dirPath string, opts GatherOptions) ([]FileInput, error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	filesCh, errCh := walkFiles(done, dirPath, opts)
+
+	var inputs []FileInput
+	for input := range filesCh {
+		inputs = append(inputs, input)
 	}
 
-	for _, pattern := range patterns {
-		matched, _ := filepath.Match(pattern, name)
-		if matched {
-			return true
-		}
+	if err := <-errCh; err != nil {
+		return nil, fmt.Errorf("error walking directory: %w", err)
 	}
-	return false
+
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].RelPath < inputs[j].RelPath })
+
+	return inputs, nil
 }
 
-func loadGitignorePatterns(dirPath string) ([]gitignore.Pattern, error) {
-	gitignorePath := filepath.Join(dirPath, ".gitignore")
+// readFile classifies and reads a single file: it peeks the first peekSize
+// bytes to detect binary content and UTF-16/UTF-8-BOM encodings, enforces
+// maxFileSize, and otherwise reads the whole file, transcoding it to UTF-8
+// if needed. Binary files are rendered per binaryMode instead of being
+// read as text; under BinaryModeSkip the rest of the file is never read
+// or hashed at all, since its content would only be discarded. If c has a
+// cached, unchanged entry for path, that's returned directly and the file
+// is never opened.
+func readFile(path string, maxFileSize int64, binaryMode BinaryMode, c *cache) (result FileResult, skip bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileResult{}, false, err
+	}
+	size := info.Size()
+
+	if maxFileSize > 0 && size > maxFileSize {
+		return FileResult{}, false, fmt.Errorf("file size %d exceeds max file size %d", size, maxFileSize)
+	}
 
-	file, err := os.Open(gitignorePath)
-	if os.IsNotExist(err) {
-		return nil, nil
+	if cached, hash, cachedSkip, ok := c.lookup(path, info, binaryMode); ok {
+		if cachedSkip {
+			return FileResult{}, true, nil
+		}
+		cached.ContentHash = hash
+		return cached, false, nil
 	}
+
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return FileResult{}, false, err
 	}
 	defer file.Close()
 
-	var patterns []gitignore.Pattern
-	scanner := bufio.NewScanner(file)
+	reader := bufio.NewReaderSize(file, peekSize)
+	peek, _ := reader.Peek(peekSize)
+	cls := classify(peek)
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	effectiveBinaryMode := binaryMode
+	if effectiveBinaryMode == "" {
+		effectiveBinaryMode = BinaryModeSkip
+	}
 
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+	// BinaryModeSkip means the file's content is never used, so there's no
+	// reason to read (and hash) the rest of it just to throw the result
+	// away -- for a large binary that's the difference between a fast skip
+	// and an OOM.
+	if cls.isBinary && effectiveBinaryMode == BinaryModeSkip {
+		return FileResult{}, true, nil
+	}
 
-		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	// Peek doesn't advance the reader, so reading it now yields the whole
+	// file, peeked bytes included.
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return FileResult{}, false, err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if cls.isBinary {
+		// Cache the raw bytes, not a binaryMode-rendered Content: the
+		// rendering depends on this run's binaryMode, not the file, so a
+		// later run with a different --binary-mode must re-render rather
+		// than replay whatever this run happened to produce.
+		c.store(path, info, hash, cacheableFile{IsBinary: true, Size: size, RawData: data})
+
+		result, skip := renderBinary(data, size, hash, binaryMode)
+		if skip {
+			return FileResult{}, true, nil
+		}
+		result.ContentHash = hash
+		return result, false, nil
 	}
 
-	return patterns, nil
+	data, err = normalizeToUTF8(data, cls.encoding)
+	if err != nil {
+		return FileResult{}, false, err
+	}
+
+	result = FileResult{Size: size, Encoding: cls.encoding, Content: string(data), ContentHash: hash}
+	c.store(path, info, hash, cacheableFile{Size: size, Encoding: cls.encoding, Content: string(data)})
+	return result, false, nil
 }
 
-func shouldIgnore(matcher gitignore.Matcher, path string, isDir bool) bool {
-	if path == "." {
-		return false
+// Gather walks dirPath, applying opts' filters, and reads every matched
+// file. Results come back sorted by RelPath and in a pre-sized slice
+// indexed by enumeration order, so the output is identical across runs
+// regardless of which worker happens to finish a given file first.
+func Gather(dirPath string, opts GatherOptions) ([]FileResult, error) {
+	inputs, err := enumerate(dirPath, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	parts := strings.Split(path, "/")
-	for i := range parts {
-		partialPath := strings.Join(parts[:i+1], "/")
-		if matcher.Match(strings.Split(partialPath, "/"), isDir && i == len(parts)-1) {
-			return true
+	var c *cache
+	if opts.Cache {
+		c, err = openCache()
+		if err != nil {
+			return nil, fmt.Errorf("opening cache: %w", err)
 		}
 	}
 
-	return false
-}
+	results := make([]FileResult, len(inputs))
+	present := make([]bool, len(inputs))
 
-func merge(done <-chan struct{}, channels ...<-chan FileResult) <-chan FileResult {
-	out := make(chan FileResult)
+	jobs := make(chan int)
 	var wg sync.WaitGroup
-	wg.Add(len(channels))
-
-	output := func(ch <-chan FileResult) {
-		defer wg.Done()
-		for result := range ch {
-			select {
-			case out <- result:
-			case <-done:
-				return
+	numWorkers := runtime.NumCPU()
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				result, skip, ferr := readFile(inputs[idx].Path, opts.MaxFileSize, opts.BinaryMode, c)
+				if skip {
+					continue
+				}
+				result.Path = inputs[idx].Path
+				result.RelPath = inputs[idx].RelPath
+				result.Err = ferr
+				results[idx] = result
+				present[idx] = true
 			}
-		}
+		}()
 	}
 
-	for _, ch := range channels {
-		go output(ch)
+	for idx := range inputs {
+		jobs <- idx
 	}
+	close(jobs)
+	wg.Wait()
 
-	go func() {
-		wg.Wait()
-		close(out)
-	}()
-
-	return out
+	out := make([]FileResult, 0, len(results))
+	for i, ok := range present {
+		if ok {
+			out = append(out, results[i])
+		}
+	}
+	return out, nil
 }
 
-func readFileContent(path string) (string, error) {
-	file, err := os.Open(path)
+// GatherStream behaves like Gather but emits FileResults on a channel as
+// soon as they're ready, in the same RelPath order, instead of buffering
+// every file's content into one slice before returning anything — useful
+// for trees too large to hold in memory at once. Cancelling ctx stops
+// processing and closes the returned channel early.
+func GatherStream(ctx context.Context, dirPath string, opts GatherOptions) (<-chan FileResult, error) {
+	inputs, err := enumerate(dirPath, opts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer file.Close()
 
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return "", err
+	var c *cache
+	if opts.Cache {
+		c, err = openCache()
+		if err != nil {
+			return nil, fmt.Errorf("opening cache: %w", err)
+		}
 	}
 
-	return string(content), nil
-}
-
-func processFile(done <-chan struct{}, in <-chan FileInput) <-chan FileResult {
-	out := make(chan FileResult)
-
-	go func() {
-		defer close(out)
+	type slot struct {
+		result FileResult
+		skip   bool
+	}
 
-		for input := range in {
-			content, err := readFileContent(input.Path)
+	// one result slot per input: workers finish in any order, but the
+	// emitter below reads the slots back in input order, so downstream
+	// sees the same sequence Gather would return.
+	slots := make([]chan slot, len(inputs))
+	for i := range slots {
+		slots[i] = make(chan slot, 1)
+	}
 
-			result := FileResult{
-				Path:    input.Path,
-				RelPath: input.RelPath,
-				Content: content,
-				Err:     err,
+	jobs := make(chan int)
+	numWorkers := runtime.NumCPU()
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			for idx := range jobs {
+				result, skip, ferr := readFile(inputs[idx].Path, opts.MaxFileSize, opts.BinaryMode, c)
+				if !skip {
+					result.Path = inputs[idx].Path
+					result.RelPath = inputs[idx].RelPath
+					result.Err = ferr
+				}
+				slots[idx] <- slot{result: result, skip: skip}
 			}
+		}()
+	}
 
+	go func() {
+		defer close(jobs)
+		for idx := range inputs {
 			select {
-			case out <- result:
-			case <-done:
+			case jobs <- idx:
+			case <-ctx.Done():
 				return
 			}
 		}
 	}()
 
-	return out
-}
-
-func Gather(dirPath string, opts GatherOptions) ([]FileResult, error) {
-	done := make(chan struct{})
-	defer close(done)
-
-	// stage 1: walk dirPath and generate file inputs
-	filesCh, walkErrCh := walkFiles(done, dirPath, opts)
-
-	// stage 2: process files with multiple workers
-	numWorkers := runtime.NumCPU()
-	workerChs := make([]<-chan FileResult, numWorkers)
-	for i := 0; i < numWorkers; i++ {
-		workerChs[i] = processFile(done, filesCh)
-	}
-
-	// stage 3: merge & collect results
-	resultsCh := merge(done, workerChs...)
-	var results []FileResult
-	for result := range resultsCh {
-		results = append(results, result)
-	}
-
-	// check for walk errors
-	if err := <-walkErrCh; err != nil {
-		return results, fmt.Errorf("error walking directory: %w", err)
-	}
+	out := make(chan FileResult)
+	go func() {
+		defer close(out)
+		for idx := range inputs {
+			select {
+			case s := <-slots[idx]:
+				if s.skip {
+					continue
+				}
+				select {
+				case out <- s.result:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
-	return results, nil
+	return out, nil
 }
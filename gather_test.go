@@ -0,0 +1,96 @@
+package filescombine
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gatherRelPaths(t *testing.T, dir string, opts GatherOptions) []string {
+	t.Helper()
+	results, err := Gather(dir, opts)
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	var relPaths []string
+	for _, r := range results {
+		relPaths = append(relPaths, r.RelPath)
+	}
+	sort.Strings(relPaths)
+	return relPaths
+}
+
+func TestGatherNestedGitignoreNegation(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\n")
+	writeFile(t, filepath.Join(dir, "sub", ".gitignore"), "!keep.log\n")
+	writeFile(t, filepath.Join(dir, "sub", "keep.log"), "keep")
+	writeFile(t, filepath.Join(dir, "sub", "drop.log"), "drop")
+	writeFile(t, filepath.Join(dir, "root.log"), "drop")
+	writeFile(t, filepath.Join(dir, "plain.txt"), "plain")
+
+	got := gatherRelPaths(t, dir, GatherOptions{IncludeHidden: true})
+
+	want := []string{".gitignore", "plain.txt", "sub/.gitignore", "sub/keep.log"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGatherNestedGitignoreScopedToItsSubtree(t *testing.T) {
+	// a/.gitignore's rules shouldn't apply outside a/, even for an
+	// identically-named file.
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a", ".gitignore"), "skip.txt\n")
+	writeFile(t, filepath.Join(dir, "a", "skip.txt"), "skip")
+	writeFile(t, filepath.Join(dir, "b", "skip.txt"), "keep")
+
+	got := gatherRelPaths(t, dir, GatherOptions{IncludeHidden: true})
+
+	want := []string{"a/.gitignore", "b/skip.txt"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGatherDeeperGitignoreOverridesShallower(t *testing.T) {
+	// the root re-includes everything *.log, but a nested .gitignore
+	// re-excludes one file within its own subtree -- deeper rules must
+	// win, since they're appended after (and so take priority over) the
+	// shallower ones.
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\n!*.log\n")
+	writeFile(t, filepath.Join(dir, "sub", ".gitignore"), "noisy.log\n")
+	writeFile(t, filepath.Join(dir, "sub", "noisy.log"), "drop")
+	writeFile(t, filepath.Join(dir, "sub", "quiet.log"), "keep")
+
+	got := gatherRelPaths(t, dir, GatherOptions{IncludeHidden: true})
+
+	want := []string{".gitignore", "sub/.gitignore", "sub/quiet.log"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package filescombine
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadGitignoreLines reads the .gitignore file in dirPath, if any, and
+// returns its raw pattern lines (comments and blanks stripped), for the
+// caller to turn into domain-scoped pathspec.Rules via
+// pathspec.FromGitignoreLines.
+func loadGitignoreLines(dirPath string) ([]string, error) {
+	return loadPatternsFile(filepath.Join(dirPath, ".gitignore"))
+}
+
+// loadGlobalGitignoreLines returns the user's global exclude patterns' raw
+// lines, for the caller to scope repo-wide via pathspec.FromGitignoreLines
+// with a nil domain. Like git itself, $XDG_CONFIG_HOME/git/ignore is only
+// a fallback: it's read only when core.excludesFile is unset, not layered
+// underneath it.
+func loadGlobalGitignoreLines() ([]string, error) {
+	if excludesFile := coreExcludesFile(); excludesFile != "" {
+		return loadPatternsFile(excludesFile)
+	}
+
+	return loadPatternsFile(xdgGitIgnorePath())
+}
+
+func loadPatternsFile(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// xdgGitIgnorePath returns the path git falls back to for global excludes
+// when core.excludesFile is unset: $XDG_CONFIG_HOME/git/ignore, or
+// ~/.config/git/ignore if XDG_CONFIG_HOME is unset.
+func xdgGitIgnorePath() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "git", "ignore")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "ignore")
+}
+
+// coreExcludesFile reads core.excludesFile out of ~/.gitconfig, expanding a
+// leading "~/" the way git itself does. Returns "" if unset or unreadable.
+func coreExcludesFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	data, err := os.Open(filepath.Join(home, ".gitconfig"))
+	if err != nil {
+		return ""
+	}
+	defer data.Close()
+
+	inCoreSection := false
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "[") {
+			inCoreSection = strings.EqualFold(strings.Trim(line, "[]"), "core")
+			continue
+		}
+
+		if !inCoreSection {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, "=")
+		if !found || strings.TrimSpace(name) != "excludesfile" {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "~/") {
+			value = filepath.Join(home, value[2:])
+		}
+		return value
+	}
+
+	return ""
+}
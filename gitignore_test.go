@@ -0,0 +1,55 @@
+package filescombine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeGitignoreTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadGlobalGitignoreLinesFallsBackToXDGWhenCoreExcludesFileUnset(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdgconfig"))
+	// no ~/.gitconfig at all, so core.excludesFile is unset.
+
+	writeGitignoreTestFile(t, filepath.Join(home, "xdgconfig", "git", "ignore"), "*.xdg\n")
+
+	lines, err := loadGlobalGitignoreLines()
+	if err != nil {
+		t.Fatalf("loadGlobalGitignoreLines: %v", err)
+	}
+	if !equalStrings(lines, []string{"*.xdg"}) {
+		t.Errorf("got %v, want the XDG fallback's lines", lines)
+	}
+}
+
+func TestLoadGlobalGitignoreLinesPrefersCoreExcludesFileOverXDG(t *testing.T) {
+	// real git treats the XDG path as a fallback used only when
+	// core.excludesFile is unset, not an additional layer -- so once
+	// core.excludesFile is set, the XDG file must not be read at all.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdgconfig"))
+
+	writeGitignoreTestFile(t, filepath.Join(home, "xdgconfig", "git", "ignore"), "*.xdg\n")
+	writeGitignoreTestFile(t, filepath.Join(home, "excludes"), "*.core\n")
+	writeGitignoreTestFile(t, filepath.Join(home, ".gitconfig"), "[core]\n\texcludesfile = ~/excludes\n")
+
+	lines, err := loadGlobalGitignoreLines()
+	if err != nil {
+		t.Fatalf("loadGlobalGitignoreLines: %v", err)
+	}
+	if !equalStrings(lines, []string{"*.core"}) {
+		t.Errorf("got %v, want only core.excludesFile's lines", lines)
+	}
+}
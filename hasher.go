@@ -0,0 +1,56 @@
+package filescombine
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+
+	"github.com/zeebo/blake3"
+)
+
+// Hasher builds fresh hash.Hash instances for a particular fingerprinting
+// algorithm, so callers that hash many files concurrently don't share
+// mutable hash state across goroutines.
+type Hasher interface {
+	New() hash.Hash
+	Name() string
+}
+
+type md5Hasher struct{}
+
+func (md5Hasher) New() hash.Hash { return md5.New() }
+func (md5Hasher) Name() string   { return "md5" }
+
+type sha1Hasher struct{}
+
+func (sha1Hasher) New() hash.Hash { return sha1.New() }
+func (sha1Hasher) Name() string   { return "sha1" }
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) New() hash.Hash { return sha256.New() }
+func (sha256Hasher) Name() string   { return "sha256" }
+
+type blake3Hasher struct{}
+
+func (blake3Hasher) New() hash.Hash { return blake3.New() }
+func (blake3Hasher) Name() string   { return "blake3" }
+
+// HasherByName resolves one of the built-in algorithm names ("md5", "sha1",
+// "sha256", "blake3") to a Hasher.
+func HasherByName(name string) (Hasher, error) {
+	switch name {
+	case "md5":
+		return md5Hasher{}, nil
+	case "sha1":
+		return sha1Hasher{}, nil
+	case "sha256":
+		return sha256Hasher{}, nil
+	case "blake3":
+		return blake3Hasher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q", name)
+	}
+}
@@ -0,0 +1,63 @@
+package filescombine
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestHasherByNameKnownAlgorithms(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantName   string
+		wantHexLen int
+	}{
+		{name: "md5", wantName: "md5", wantHexLen: 32},
+		{name: "sha1", wantName: "sha1", wantHexLen: 40},
+		{name: "sha256", wantName: "sha256", wantHexLen: 64},
+		{name: "blake3", wantName: "blake3", wantHexLen: 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := HasherByName(tt.name)
+			if err != nil {
+				t.Fatalf("HasherByName(%q): %v", tt.name, err)
+			}
+			if hasher.Name() != tt.wantName {
+				t.Errorf("Name() = %q, want %q", hasher.Name(), tt.wantName)
+			}
+
+			h := hasher.New()
+			h.Write([]byte("hello"))
+			sum := hex.EncodeToString(h.Sum(nil))
+			if len(sum) != tt.wantHexLen {
+				t.Errorf("hex digest length = %d, want %d (digest %q)", len(sum), tt.wantHexLen, sum)
+			}
+		})
+	}
+}
+
+func TestHasherByNameUnknown(t *testing.T) {
+	if _, err := HasherByName("md4"); err == nil {
+		t.Fatal("expected an error for an unknown algorithm name")
+	}
+}
+
+func TestHasherNewReturnsIndependentState(t *testing.T) {
+	hasher, err := HasherByName("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := hasher.New()
+	a.Write([]byte("first"))
+
+	b := hasher.New()
+	b.Write([]byte("second"))
+
+	sumA := hex.EncodeToString(a.Sum(nil))
+	sumB := hex.EncodeToString(b.Sum(nil))
+	if sumA == sumB {
+		t.Errorf("two New() hashers should not share state, got identical sums %q", sumA)
+	}
+}
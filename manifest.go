@@ -0,0 +1,264 @@
+package filescombine
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ManifestEntry is the fingerprint of a single gathered file.
+type ManifestEntry struct {
+	RelPath string
+	Sum     []byte
+	Size    int64
+}
+
+// Hex returns the entry's digest as a lowercase hex string.
+func (e ManifestEntry) Hex() string {
+	return hex.EncodeToString(e.Sum)
+}
+
+// GatherPaths walks dirPath applying the same gitignore/extension/hidden
+// filters as Gather, but returns only the matched paths without reading
+// file content. It's the entry point for pipelines, like the manifest
+// builder, that need to touch files directly (e.g. to stream-hash them)
+// rather than buffer their content as a string.
+func GatherPaths(dirPath string, opts GatherOptions) ([]FileInput, error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	filesCh, errCh := walkFiles(done, dirPath, opts)
+
+	var inputs []FileInput
+	for input := range filesCh {
+		inputs = append(inputs, input)
+	}
+
+	if err := <-errCh; err != nil {
+		return inputs, fmt.Errorf("error walking directory: %w", err)
+	}
+
+	return inputs, nil
+}
+
+// BuildManifest walks dirPath with opts and fingerprints every matched file
+// with hasher, using a worker per CPU the same way Gather does. Entries are
+// returned sorted by RelPath so the manifest is deterministic across runs.
+func BuildManifest(dirPath string, opts GatherOptions, hasher Hasher) ([]ManifestEntry, error) {
+	inputs, err := GatherPaths(dirPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ManifestEntry, len(inputs))
+	errs := make([]error, len(inputs))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	numWorkers := runtime.NumCPU()
+
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				sum, size, err := hashFile(hasher, inputs[i].Path)
+				entries[i] = ManifestEntry{RelPath: inputs[i].RelPath, Sum: sum, Size: size}
+				errs[i] = err
+			}
+		}()
+	}
+
+	for i := range inputs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return entries, fmt.Errorf("hashing %s: %w", inputs[i].RelPath, err)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].RelPath < entries[j].RelPath })
+
+	return entries, nil
+}
+
+func hashFile(hasher Hasher, path string) ([]byte, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	h := hasher.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return h.Sum(nil), size, nil
+}
+
+// ManifestFormat selects how WriteManifest renders entries.
+type ManifestFormat string
+
+const (
+	ManifestFormatPlain ManifestFormat = "plain"
+	ManifestFormatJSONL ManifestFormat = "jsonl"
+	ManifestFormatSRI   ManifestFormat = "sri"
+)
+
+type manifestLine struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// WriteManifest renders entries to w in the given format. hasher is only
+// needed to label SRI digests with their algorithm name (e.g. "sha256-...").
+func WriteManifest(w io.Writer, entries []ManifestEntry, format ManifestFormat, hasher Hasher) error {
+	for _, e := range entries {
+		var line string
+		switch format {
+		case ManifestFormatJSONL:
+			b, err := json.Marshal(manifestLine{Path: e.RelPath, Hash: e.Hex(), Size: e.Size})
+			if err != nil {
+				return err
+			}
+			line = string(b)
+		case ManifestFormatSRI:
+			line = fmt.Sprintf("%s\t%s-%s", e.RelPath, hasher.Name(), base64.StdEncoding.EncodeToString(e.Sum))
+		case ManifestFormatPlain, "":
+			line = fmt.Sprintf("%s\t%s", e.Hex(), e.RelPath)
+		default:
+			return fmt.Errorf("unknown manifest format %q", format)
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadManifest parses a manifest previously written by WriteManifest in the
+// given format, keyed by RelPath, for use by VerifyManifest.
+func ReadManifest(r io.Reader, format ManifestFormat) (map[string]ManifestEntry, error) {
+	entries := make(map[string]ManifestEntry)
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		switch format {
+		case ManifestFormatJSONL:
+			var ml manifestLine
+			if err := json.Unmarshal([]byte(line), &ml); err != nil {
+				return nil, fmt.Errorf("parsing manifest line %q: %w", line, err)
+			}
+			sum, err := hex.DecodeString(ml.Hash)
+			if err != nil {
+				return nil, fmt.Errorf("parsing manifest line %q: %w", line, err)
+			}
+			entries[ml.Path] = ManifestEntry{RelPath: ml.Path, Sum: sum, Size: ml.Size}
+
+		case ManifestFormatSRI:
+			path, digest, ok := strings.Cut(line, "\t")
+			if !ok {
+				return nil, fmt.Errorf("parsing manifest line %q: expected a tab-separated path and digest", line)
+			}
+			_, b64, ok := strings.Cut(digest, "-")
+			if !ok {
+				return nil, fmt.Errorf("parsing manifest line %q: expected an SRI-style algo-digest", line)
+			}
+			sum, err := base64.StdEncoding.DecodeString(b64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing manifest line %q: %w", line, err)
+			}
+			entries[path] = ManifestEntry{RelPath: path, Sum: sum}
+
+		case ManifestFormatPlain, "":
+			hexSum, path, ok := strings.Cut(line, "\t")
+			if !ok {
+				return nil, fmt.Errorf("parsing manifest line %q: expected a tab-separated hash and path", line)
+			}
+			sum, err := hex.DecodeString(hexSum)
+			if err != nil {
+				return nil, fmt.Errorf("parsing manifest line %q: %w", line, err)
+			}
+			entries[path] = ManifestEntry{RelPath: path, Sum: sum}
+
+		default:
+			return nil, fmt.Errorf("unknown manifest format %q", format)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// VerifyReport summarizes how a directory tree has drifted from a manifest.
+type VerifyReport struct {
+	Added    []string
+	Deleted  []string
+	Modified []string
+}
+
+// Clean reports whether the tree matched the manifest exactly.
+func (r VerifyReport) Clean() bool {
+	return len(r.Added) == 0 && len(r.Deleted) == 0 && len(r.Modified) == 0
+}
+
+// VerifyManifest re-hashes dirPath with opts and hasher, and diffs the
+// result against a previously recorded manifest.
+func VerifyManifest(dirPath string, opts GatherOptions, hasher Hasher, recorded map[string]ManifestEntry) (VerifyReport, error) {
+	current, err := BuildManifest(dirPath, opts, hasher)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	seen := make(map[string]bool, len(current))
+	var report VerifyReport
+
+	for _, entry := range current {
+		seen[entry.RelPath] = true
+		prior, ok := recorded[entry.RelPath]
+		if !ok {
+			report.Added = append(report.Added, entry.RelPath)
+			continue
+		}
+		if string(prior.Sum) != string(entry.Sum) {
+			report.Modified = append(report.Modified, entry.RelPath)
+		}
+	}
+
+	for relPath := range recorded {
+		if !seen[relPath] {
+			report.Deleted = append(report.Deleted, relPath)
+		}
+	}
+
+	sort.Strings(report.Added)
+	sort.Strings(report.Deleted)
+	sort.Strings(report.Modified)
+
+	return report, nil
+}
@@ -0,0 +1,159 @@
+package filescombine
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeManifestTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildManifestSortedByRelPath(t *testing.T) {
+	dir := t.TempDir()
+	writeManifestTestFile(t, filepath.Join(dir, "b.txt"), "bbb")
+	writeManifestTestFile(t, filepath.Join(dir, "a.txt"), "aaa")
+
+	hasher, err := HasherByName("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := BuildManifest(dir, GatherOptions{IncludeHidden: true}, hasher)
+	if err != nil {
+		t.Fatalf("BuildManifest: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].RelPath != "a.txt" || entries[1].RelPath != "b.txt" {
+		t.Errorf("entries not sorted by RelPath: %+v", entries)
+	}
+	if entries[0].Size != 3 {
+		t.Errorf("a.txt Size = %d, want 3", entries[0].Size)
+	}
+}
+
+func TestWriteReadManifestRoundTrip(t *testing.T) {
+	hasher, err := HasherByName("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []ManifestEntry{
+		{RelPath: "a.txt", Sum: hasher.New().Sum([]byte("irrelevant-seed")), Size: 3},
+		{RelPath: "sub/b.txt", Sum: hasher.New().Sum(nil), Size: 0},
+	}
+
+	for _, format := range []ManifestFormat{ManifestFormatPlain, ManifestFormatJSONL, ManifestFormatSRI} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteManifest(&buf, entries, format, hasher); err != nil {
+				t.Fatalf("WriteManifest: %v", err)
+			}
+
+			got, err := ReadManifest(&buf, format)
+			if err != nil {
+				t.Fatalf("ReadManifest: %v", err)
+			}
+
+			for _, want := range entries {
+				entry, ok := got[want.RelPath]
+				if !ok {
+					t.Fatalf("missing entry for %s", want.RelPath)
+				}
+				if !bytes.Equal(entry.Sum, want.Sum) {
+					t.Errorf("%s: Sum = %x, want %x", want.RelPath, entry.Sum, want.Sum)
+				}
+				if format == ManifestFormatPlain && entry.Size != 0 {
+					// plain format doesn't record size at all
+					t.Errorf("plain format shouldn't carry a size, got %d", entry.Size)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyManifestDetectsDrift(t *testing.T) {
+	dir := t.TempDir()
+	writeManifestTestFile(t, filepath.Join(dir, "kept.txt"), "kept")
+	writeManifestTestFile(t, filepath.Join(dir, "changed.txt"), "before")
+	writeManifestTestFile(t, filepath.Join(dir, "deleted.txt"), "gone-soon")
+
+	hasher, err := HasherByName("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := GatherOptions{IncludeHidden: true}
+
+	before, err := BuildManifest(dir, opts, hasher)
+	if err != nil {
+		t.Fatalf("BuildManifest: %v", err)
+	}
+	recorded := make(map[string]ManifestEntry, len(before))
+	for _, e := range before {
+		recorded[e.RelPath] = e
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("after"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+	writeManifestTestFile(t, filepath.Join(dir, "added.txt"), "new")
+
+	report, err := VerifyManifest(dir, opts, hasher, recorded)
+	if err != nil {
+		t.Fatalf("VerifyManifest: %v", err)
+	}
+
+	if report.Clean() {
+		t.Fatal("expected VerifyManifest to report drift")
+	}
+	if !equalStrings(report.Added, []string{"added.txt"}) {
+		t.Errorf("Added = %v, want [added.txt]", report.Added)
+	}
+	if !equalStrings(report.Deleted, []string{"deleted.txt"}) {
+		t.Errorf("Deleted = %v, want [deleted.txt]", report.Deleted)
+	}
+	if !equalStrings(report.Modified, []string{"changed.txt"}) {
+		t.Errorf("Modified = %v, want [changed.txt]", report.Modified)
+	}
+}
+
+func TestVerifyManifestCleanWhenUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	writeManifestTestFile(t, filepath.Join(dir, "a.txt"), "aaa")
+
+	hasher, err := HasherByName("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := GatherOptions{IncludeHidden: true}
+
+	entries, err := BuildManifest(dir, opts, hasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := make(map[string]ManifestEntry, len(entries))
+	for _, e := range entries {
+		recorded[e.RelPath] = e
+	}
+
+	report, err := VerifyManifest(dir, opts, hasher, recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Clean() {
+		t.Errorf("expected a clean report, got %+v", report)
+	}
+}
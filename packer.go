@@ -0,0 +1,340 @@
+package filescombine
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bnmoch3/files-combine/tokens"
+)
+
+// TruncationStrategy controls how a file that alone would overflow the
+// remaining token budget gets shrunk to fit.
+type TruncationStrategy string
+
+const (
+	TruncateHead        TruncationStrategy = "head"
+	TruncateTail        TruncationStrategy = "tail"
+	TruncateHeadTail    TruncationStrategy = "head+tail"
+	TruncateSymbolsOnly TruncationStrategy = "symbols-only"
+)
+
+const elisionMarker = "... [elided] ..."
+
+// packedFile is a FileResult annotated with its place in the token budget.
+type packedFile struct {
+	FileResult
+	TokenCount int
+	Truncated  bool
+	Omitted    bool
+}
+
+// orderForPacking returns results reordered per order, which is one of
+// "size-asc", "size-desc", or "path" (the default). "gitignore-priority"
+// is accepted as an alias of "path": Gather already sorts its results by
+// RelPath for deterministic output, so there's no separate walk order
+// left for it to mean.
+func orderForPacking(results []FileResult, order string) []FileResult {
+	ordered := make([]FileResult, len(results))
+	copy(ordered, results)
+
+	switch order {
+	case "size-asc":
+		sort.SliceStable(ordered, func(i, j int) bool { return len(ordered[i].Content) < len(ordered[j].Content) })
+	case "size-desc":
+		sort.SliceStable(ordered, func(i, j int) bool { return len(ordered[i].Content) > len(ordered[j].Content) })
+	case "path", "gitignore-priority", "":
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].RelPath < ordered[j].RelPath })
+	}
+
+	return ordered
+}
+
+// packToBudget greedily includes results up to tok's budget, in the given
+// order, truncating the file that first overflows per strategy rather than
+// dropping it outright. Everything after that still gets a budget-aware
+// pass so a tiny file following a huge omitted one can still fit.
+//
+// c, if non-nil, is consulted (and updated) for each file's full-content
+// token count under tokenizerName, keyed by FileResult.ContentHash, so a
+// later run over unchanged files can plan without re-tokenizing them.
+func packToBudget(results []FileResult, order string, budget int, strategy TruncationStrategy, tok tokens.Tokenizer, tokenizerName string, c *cache) []packedFile {
+	ordered := orderForPacking(results, order)
+	planned := make([]packedFile, 0, len(ordered))
+	remaining := budget
+
+	for _, r := range ordered {
+		if r.Err != nil {
+			planned = append(planned, packedFile{FileResult: r})
+			continue
+		}
+
+		count := tokenCount(r, tok, tokenizerName, c)
+
+		if remaining <= 0 {
+			planned = append(planned, packedFile{FileResult: r, TokenCount: count, Omitted: true})
+			continue
+		}
+
+		if count <= remaining {
+			planned = append(planned, packedFile{FileResult: r, TokenCount: count})
+			remaining -= count
+			continue
+		}
+
+		truncated := r
+		truncated.Content = truncate(r, strategy, tok, remaining)
+		truncatedCount := tok.Count(truncated.Content)
+		planned = append(planned, packedFile{FileResult: truncated, TokenCount: truncatedCount, Truncated: true})
+
+		remaining -= truncatedCount
+		if remaining < 0 {
+			remaining = 0
+		}
+	}
+
+	return planned
+}
+
+// tokenCount returns tok's count for r.Content, reusing a cached count
+// under r.ContentHash when c has one and recording a fresh count back to
+// it otherwise.
+func tokenCount(r FileResult, tok tokens.Tokenizer, tokenizerName string, c *cache) int {
+	if r.ContentHash != "" {
+		if count, ok := c.tokenCount(r.ContentHash, tokenizerName); ok {
+			return count
+		}
+	}
+
+	count := tok.Count(r.Content)
+	if r.ContentHash != "" {
+		c.storeTokenCount(r.ContentHash, tokenizerName, count)
+	}
+	return count
+}
+
+func truncate(r FileResult, strategy TruncationStrategy, tok tokens.Tokenizer, budget int) string {
+	content := r.Content
+
+	if strategy == TruncateSymbolsOnly {
+		if stripped, ok := symbolsOnly(content, filepath.Ext(r.RelPath)); ok {
+			content = stripped
+			if tok.Count(content) <= budget {
+				return content
+			}
+			// still too big (e.g. one huge top-level decl): fall through
+			// to a head truncation of the stripped content
+		} else {
+			strategy = TruncateHead
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+
+	switch strategy {
+	case TruncateTail:
+		return truncateLines(lines, budget, tok, false)
+	case TruncateHeadTail:
+		headBudget := budget / 2
+		tailBudget := budget - headBudget
+		head := truncateLines(lines, headBudget, tok, true)
+		tail := truncateLines(lines, tailBudget, tok, false)
+		return head + "\n" + elisionMarker + "\n" + tail
+	default: // TruncateHead, or symbols-only's post-strip fallback
+		return truncateLines(lines, budget, tok, true)
+	}
+}
+
+// truncateLines keeps the largest prefix (fromStart) or suffix of lines
+// whose token count fits budget, found by binary search since Count isn't
+// necessarily linear in line count for non-byte tokenizers.
+func truncateLines(lines []string, budget int, tok tokens.Tokenizer, fromStart bool) string {
+	lo, hi, best := 0, len(lines), 0
+
+	for lo <= hi {
+		mid := (lo + hi) / 2
+
+		var candidate string
+		if fromStart {
+			candidate = strings.Join(lines[:mid], "\n")
+		} else {
+			candidate = strings.Join(lines[len(lines)-mid:], "\n")
+		}
+
+		if tok.Count(candidate) <= budget {
+			best = mid
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	omitted := len(lines) - best
+	note := fmt.Sprintf("%s (%d lines omitted)", elisionMarker, omitted)
+
+	if fromStart {
+		kept := strings.Join(lines[:best], "\n")
+		if omitted == 0 {
+			return kept
+		}
+		return kept + "\n" + note
+	}
+
+	kept := strings.Join(lines[len(lines)-best:], "\n")
+	if omitted == 0 {
+		return kept
+	}
+	return note + "\n" + kept
+}
+
+// symbolsOnly keeps top-level declarations and drops function/method/class
+// bodies, for extensions we know how to parse structurally (lightweight,
+// not a real AST pass). ok is false for unsupported extensions.
+func symbolsOnly(content, ext string) (string, bool) {
+	switch ext {
+	case ".go", ".js", ".jsx", ".ts", ".tsx":
+		return stripBracedBodies(content), true
+	case ".py":
+		return stripIndentedBodies(content), true
+	default:
+		return content, false
+	}
+}
+
+// braceFrame tracks one open brace block stripBracedBodies is currently
+// inside: openDepth is the overall brace depth once the block's opening
+// line has been applied, so the frame pops as soon as depth falls back
+// below it. elide is false for a struct/interface/class -- a container
+// whose contents (field lists, method signatures) are kept verbatim -- and
+// true for a func/method, whose statement body is what actually gets
+// replaced by elisionMarker.
+type braceFrame struct {
+	openDepth     int
+	elide         bool
+	markerEmitted bool
+}
+
+// isContainerOpener reports whether a line that opens a brace block is a
+// struct/interface/class declaration rather than a function body: its
+// contents are declarations (fields, method signatures), not executable
+// statements, so they're never elided.
+func isContainerOpener(line string) bool {
+	return strings.Contains(line, "struct") || strings.Contains(line, "interface") || strings.Contains(line, "class ")
+}
+
+// stripBracedBodies keeps every declaration verbatim -- top-level lines,
+// struct/interface/class field and method-signature lists, and the
+// signature line of a nested func -- eliding only what's actually
+// executable: the statements inside a func or method body. Brace counting
+// is naive (it doesn't understand strings or comments containing braces),
+// which is the tradeoff for staying a regex/line pass rather than a real
+// parser.
+func stripBracedBodies(content string) string {
+	lines := strings.Split(content, "\n")
+	var out []string
+	var stack []braceFrame
+	depth := 0
+
+	for _, line := range lines {
+		suppressed := len(stack) > 0 && stack[len(stack)-1].elide
+
+		if suppressed {
+			top := &stack[len(stack)-1]
+			if !top.markerEmitted {
+				out = append(out, "    "+elisionMarker)
+				top.markerEmitted = true
+			}
+		} else {
+			out = append(out, line)
+		}
+
+		opens := strings.Count(line, "{")
+		closes := strings.Count(line, "}")
+
+		if !suppressed && opens > closes {
+			stack = append(stack, braceFrame{
+				openDepth: depth + opens - closes,
+				elide:     !isContainerOpener(line),
+			})
+		}
+
+		depth += opens - closes
+		if depth < 0 {
+			depth = 0
+		}
+		for len(stack) > 0 && depth < stack[len(stack)-1].openDepth {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// indentFrame tracks one open def/class body stripIndentedBodies is
+// currently inside, identified by the indentation of its own signature
+// line: a line dedenting back to (or past) that indent has left the body.
+type indentFrame struct {
+	indent        int
+	markerEmitted bool
+}
+
+// stripIndentedBodies keeps every def/class signature line, at any nesting
+// depth, verbatim -- including a method nested inside a class -- and
+// elides only the executable statements inside each body, using
+// indentation as the block boundary the way Python itself does.
+func stripIndentedBodies(content string) string {
+	lines := strings.Split(content, "\n")
+	var out []string
+	var stack []indentFrame
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			if len(stack) == 0 {
+				out = append(out, line)
+			}
+			continue
+		}
+
+		indent := indentWidth(line)
+		for len(stack) > 0 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		if strings.HasPrefix(trimmed, "def ") || strings.HasPrefix(trimmed, "class ") {
+			out = append(out, line)
+			stack = append(stack, indentFrame{indent: indent})
+			continue
+		}
+
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if !top.markerEmitted {
+				out = append(out, strings.Repeat(" ", top.indent+4)+elisionMarker)
+				top.markerEmitted = true
+			}
+			continue
+		}
+
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
@@ -0,0 +1,262 @@
+package filescombine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bnmoch3/files-combine/tokens"
+)
+
+func bytesTokenizer(t *testing.T) tokens.Tokenizer {
+	t.Helper()
+	tok, err := tokens.ByName("bytes/4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestTruncateLinesFromStart(t *testing.T) {
+	tok := bytesTokenizer(t)
+	lines := []string{"aaaa", "bbbb", "cccc", "dddd"} // 4 bytes + "\n" each when joined
+
+	got := truncateLines(lines, 100, tok, true)
+	if got != strings.Join(lines, "\n") {
+		t.Errorf("a budget bigger than the content should keep everything, got %q", got)
+	}
+
+	got = truncateLines(lines, 0, tok, true)
+	if !strings.Contains(got, elisionMarker) {
+		t.Errorf("a zero budget should keep nothing but the elision marker, got %q", got)
+	}
+	if strings.Contains(got, "aaaa") {
+		t.Errorf("zero budget should keep no content, got %q", got)
+	}
+}
+
+func TestTruncateLinesFromEnd(t *testing.T) {
+	tok := bytesTokenizer(t)
+	lines := []string{"aaaa", "bbbb", "cccc", "dddd"}
+
+	got := truncateLines(lines, 100, tok, false)
+	if got != strings.Join(lines, "\n") {
+		t.Errorf("a budget bigger than the content should keep everything, got %q", got)
+	}
+
+	// budget for exactly the last line ("dddd" = 4 bytes = 1 token at
+	// bytes/4): the kept suffix should be just that line, with an
+	// omission note above it.
+	got = truncateLines(lines, 1, tok, false)
+	if !strings.HasSuffix(got, "dddd") {
+		t.Errorf("expected the tail to end with the last line, got %q", got)
+	}
+	if strings.Contains(got, "aaaa") {
+		t.Errorf("expected earlier lines to be dropped, got %q", got)
+	}
+}
+
+func TestTruncateLinesSingleLine(t *testing.T) {
+	tok := bytesTokenizer(t)
+	lines := []string{"only line"}
+
+	got := truncateLines(lines, 100, tok, true)
+	if got != "only line" {
+		t.Errorf("got %q, want the single line unchanged", got)
+	}
+
+	got = truncateLines(lines, 0, tok, true)
+	if strings.Contains(got, "only line") {
+		t.Errorf("a zero budget shouldn't keep the only line, got %q", got)
+	}
+}
+
+func TestTruncateHeadTailSplitsTheBudget(t *testing.T) {
+	tok := bytesTokenizer(t)
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = strings.Repeat("x", 4) // 1 token each
+	}
+	r := FileResult{Content: strings.Join(lines, "\n")}
+
+	got := truncate(r, TruncateHeadTail, tok, 4)
+	if !strings.Contains(got, elisionMarker) {
+		t.Errorf("expected an elision marker in a head+tail truncation, got %q", got)
+	}
+}
+
+func TestSymbolsOnlyUnsupportedExtensionFallsBackToHead(t *testing.T) {
+	content := strings.Repeat("line\n", 50)
+	r := FileResult{RelPath: "data.csv", Content: content}
+	tok := bytesTokenizer(t)
+
+	got := truncate(r, TruncateSymbolsOnly, tok, 2)
+	if strings.Contains(got, elisionMarker) == false {
+		t.Errorf("expected head-truncation fallback to still elide, got %q", got)
+	}
+}
+
+func TestStripBracedBodiesKeepsTopLevel(t *testing.T) {
+	content := `package main
+
+func main() {
+	x := 1
+	if x == 1 {
+		println("hi")
+	}
+}
+
+func other() {}
+`
+	got := stripBracedBodies(content)
+
+	if strings.Contains(got, "println") {
+		t.Errorf("expected the nested block body to be elided, got %q", got)
+	}
+	if !strings.Contains(got, "func main() {") {
+		t.Errorf("expected the top-level func signature to survive, got %q", got)
+	}
+	if !strings.Contains(got, "func other() {}") {
+		t.Errorf("expected a same-line empty body to survive untouched, got %q", got)
+	}
+}
+
+func TestStripBracedBodiesKeepsStructFields(t *testing.T) {
+	content := `package main
+
+type Foo struct {
+	A int
+	B string
+}
+
+func (f Foo) Method() {
+	x := f.A
+	_ = x
+}
+`
+	got := stripBracedBodies(content)
+
+	if !strings.Contains(got, "A int") || !strings.Contains(got, "B string") {
+		t.Errorf("expected the struct's field list to survive untouched, got %q", got)
+	}
+	if strings.Contains(got, "x := f.A") {
+		t.Errorf("expected the method's statement body to be elided, got %q", got)
+	}
+	if !strings.Contains(got, "func (f Foo) Method() {") {
+		t.Errorf("expected the method signature to survive, got %q", got)
+	}
+}
+
+func TestStripBracedBodiesKeepsNestedMethodSignatures(t *testing.T) {
+	content := `class Foo {
+	render() {
+		return 1
+	}
+}
+`
+	got := stripBracedBodies(content)
+
+	if !strings.Contains(got, "render() {") {
+		t.Errorf("expected the nested method signature to survive, got %q", got)
+	}
+	if strings.Contains(got, "return 1") {
+		t.Errorf("expected the method's statement body to be elided, got %q", got)
+	}
+}
+
+func TestStripIndentedBodiesKeepsNestedSignatures(t *testing.T) {
+	content := `class Foo:
+    def bar(self):
+        x = 1
+        return x
+
+def top():
+    pass
+`
+	got := stripIndentedBodies(content)
+
+	if strings.Contains(got, "return x") {
+		t.Errorf("expected the indented body to be elided, got %q", got)
+	}
+	if !strings.Contains(got, "class Foo:") {
+		t.Errorf("expected the top-level class line to survive, got %q", got)
+	}
+	if !strings.Contains(got, "def top():") {
+		t.Errorf("expected the top-level def to survive, got %q", got)
+	}
+	if !strings.Contains(got, "def bar(self):") {
+		t.Errorf("expected the nested method signature to survive, got %q", got)
+	}
+}
+
+func TestPackToBudgetZeroOmitsEverything(t *testing.T) {
+	tok := bytesTokenizer(t)
+	results := []FileResult{
+		{RelPath: "a.txt", Content: "aaaa"},
+		{RelPath: "b.txt", Content: "bbbb"},
+	}
+
+	planned := packToBudget(results, "path", 0, TruncateHead, tok, tok.Name(), nil)
+
+	for _, p := range planned {
+		if !p.Omitted {
+			t.Errorf("%s: expected Omitted with a zero budget", p.RelPath)
+		}
+	}
+}
+
+func TestPackToBudgetExactFitIsNotTruncated(t *testing.T) {
+	tok := bytesTokenizer(t)
+	results := []FileResult{
+		{RelPath: "a.txt", Content: "aaaa"}, // exactly 1 token at bytes/4
+	}
+
+	planned := packToBudget(results, "path", 1, TruncateHead, tok, tok.Name(), nil)
+
+	if len(planned) != 1 {
+		t.Fatalf("expected 1 planned file, got %d", len(planned))
+	}
+	if planned[0].Truncated || planned[0].Omitted {
+		t.Errorf("a file that exactly fits the budget shouldn't be truncated or omitted: %+v", planned[0])
+	}
+	if planned[0].Content != "aaaa" {
+		t.Errorf("content should be unchanged, got %q", planned[0].Content)
+	}
+}
+
+func TestPackToBudgetOverflowingFileIsTruncatedNotDropped(t *testing.T) {
+	tok := bytesTokenizer(t)
+	results := []FileResult{
+		{RelPath: "a.txt", Content: strings.Repeat("x", 100)},
+	}
+
+	planned := packToBudget(results, "path", 2, TruncateHead, tok, tok.Name(), nil)
+
+	if len(planned) != 1 {
+		t.Fatalf("expected 1 planned file, got %d", len(planned))
+	}
+	if !planned[0].Truncated {
+		t.Errorf("an overflowing file should be truncated, not dropped entirely: %+v", planned[0])
+	}
+	if planned[0].Omitted {
+		t.Errorf("a truncated file should not also be marked Omitted")
+	}
+}
+
+func TestOrderForPackingGitignorePriorityAliasesPath(t *testing.T) {
+	results := []FileResult{
+		{RelPath: "b.txt"},
+		{RelPath: "a.txt"},
+	}
+
+	byPath := orderForPacking(results, "path")
+	byAlias := orderForPacking(results, "gitignore-priority")
+
+	for i := range byPath {
+		if byPath[i].RelPath != byAlias[i].RelPath {
+			t.Fatalf("gitignore-priority and path diverged at %d: %q vs %q", i, byAlias[i].RelPath, byPath[i].RelPath)
+		}
+	}
+	if byPath[0].RelPath != "a.txt" {
+		t.Errorf("expected path order, got %v", byPath)
+	}
+}
@@ -0,0 +1,222 @@
+// Package pathspec compiles ignore/include patterns from every source
+// Gather filters by — CLI --ignore flags, shared rule files, repo and
+// global .gitignore files, the --ext allow-list, and hidden-file
+// exclusion — into one ordered, last-match-wins chain, the same
+// evaluation model git's own ignore rules and Syncthing's .stignore use.
+// Folding all of them into one Chain, rather than resolving each source
+// independently before consulting the next, is what lets a rule from any
+// source override one from any other (a --ignore re-include pulling back
+// a path .gitignore excluded, and vice versa). A single gitignore-style
+// pattern syntax (anchored /foo, doublestar globs, leading ! to
+// re-include) replaces the old basename-only filepath.Match behavior, so
+// a pattern like "vendor/**" matches the whole relative path, not just a
+// file's name.
+package pathspec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// Rule is one compiled pathspec entry.
+type Rule struct {
+	Include   bool
+	Pattern   gitignore.Pattern
+	FilesOnly bool   // if true, this rule never matches directories
+	Source    string // where the rule came from, for diagnostics
+}
+
+// Chain is an ordered set of Rules evaluated last-match-wins: later rules
+// override earlier ones, and a rule with Include=true re-includes a path
+// an earlier rule excluded.
+type Chain struct {
+	rules []Rule
+}
+
+// New builds a Chain from already-compiled rules, in priority order
+// (lowest first).
+func New(rules []Rule) *Chain {
+	return &Chain{rules: rules}
+}
+
+// Match reports whether path (its slash-separated segments, relative to
+// the pathspec root) should be excluded.
+func (c *Chain) Match(path []string, isDir bool) bool {
+	excluded := false
+	for _, rule := range c.rules {
+		if rule.FilesOnly && isDir {
+			continue
+		}
+		if rule.Pattern.Match(path, isDir) != gitignore.NoMatch {
+			excluded = !rule.Include
+		}
+	}
+	return excluded
+}
+
+// CompileIgnorePatterns turns CLI-style --ignore patterns into Rules.
+// Each pattern is a gitignore-style glob (so "vendor/**" matches the full
+// relative path, not just a basename); a leading "!" marks a re-include.
+// filesOnly applies uniformly to every pattern in this call, matching
+// GatherOptions.IgnoreFilesOnly's existing all-or-nothing CLI flag.
+func CompileIgnorePatterns(patterns []string, filesOnly bool, source string) []Rule {
+	rules := make([]Rule, 0, len(patterns))
+	for _, raw := range patterns {
+		include, pattern := splitNegation(raw)
+		rules = append(rules, Rule{
+			Include:   include,
+			Pattern:   gitignore.ParsePattern(pattern, nil),
+			FilesOnly: filesOnly,
+			Source:    source,
+		})
+	}
+	return rules
+}
+
+func splitNegation(line string) (include bool, pattern string) {
+	if strings.HasPrefix(line, "!") {
+		return true, line[1:]
+	}
+	return false, line
+}
+
+// FromGitignoreLines turns gitignore-syntax lines (as read from a
+// .gitignore file or git's global excludes) into Rules scoped to domain
+// (nil for repo-wide), splitting off each line's leading "!" the same
+// way CompileIgnorePatterns does for --ignore patterns. Routing
+// .gitignore lines through the same negation convention as every other
+// rule source is what lets them sit in the same Chain and interact with
+// --ignore/--ignore-file rules' negations, instead of being resolved by
+// a separate gitignore.Matcher first.
+func FromGitignoreLines(lines []string, domain []string, source string) []Rule {
+	rules := make([]Rule, 0, len(lines))
+	for _, raw := range lines {
+		include, pattern := splitNegation(raw)
+		rules = append(rules, Rule{
+			Include: include,
+			Pattern: gitignore.ParsePattern(pattern, domain),
+			Source:  source,
+		})
+	}
+	return rules
+}
+
+// ExtensionRule returns the Rule enforcing Extensions' allow-list:
+// applied only to files (a non-matching directory is never pruned, so
+// its descendants are still considered individually), and meant to sit
+// first/lowest-priority in the chain so a later rule — a gitignore
+// negation, a --ignore re-include — can still pull one path back in
+// despite its extension.
+func ExtensionRule(extensions []string, source string) Rule {
+	exts := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts[ext] = true
+	}
+	return Rule{Pattern: extensionPattern{exts: exts}, Source: source}
+}
+
+// extensionPattern is a gitignore.Pattern that excludes any file whose
+// extension isn't in exts; it never matches a directory, since the
+// extension filter governs which files end up in the output, not which
+// directories get walked.
+type extensionPattern struct {
+	exts map[string]bool
+}
+
+func (p extensionPattern) Match(path []string, isDir bool) gitignore.MatchResult {
+	if isDir || len(path) == 0 {
+		return gitignore.NoMatch
+	}
+	if p.exts[filepath.Ext(path[len(path)-1])] {
+		return gitignore.NoMatch
+	}
+	return gitignore.Exclude
+}
+
+// HiddenRule returns the Rule that excludes any dotfile or dotdirectory:
+// an unanchored ".*" gitignore pattern already matches any path segment
+// starting with "." at any depth, the same scope IncludeHidden=false
+// covered as a standalone check before.
+func HiddenRule(source string) Rule {
+	return Rule{Pattern: gitignore.ParsePattern(".*", nil), Source: source}
+}
+
+// LoadRulesFile parses a pathspec rules file: one pattern per line, blank
+// lines and "#"-led comments skipped, a leading "!" re-includes, and an
+// "#include path/to/file" directive splices in another rules file
+// (resolved relative to the including file's directory) so teams can
+// compose a shared set of rules across files or projects.
+func LoadRulesFile(path string) ([]Rule, error) {
+	return loadRulesFile(path, make(map[string]bool))
+}
+
+func loadRulesFile(path string, seen map[string]bool) ([]Rule, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if seen[abs] {
+		return nil, fmt.Errorf("circular #include of %s", path)
+	}
+	seen[abs] = true
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseRules(file, filepath.Dir(path), path, seen)
+}
+
+func parseRules(r io.Reader, baseDir, source string, seen map[string]bool) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#include ") {
+			includePath := strings.TrimSpace(strings.TrimPrefix(line, "#include "))
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(baseDir, includePath)
+			}
+			included, err := loadRulesFile(includePath, seen)
+			if err != nil {
+				return nil, fmt.Errorf("including %s: %w", includePath, err)
+			}
+			rules = append(rules, included...)
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		include, pattern := splitNegation(line)
+		rules = append(rules, Rule{
+			Include: include,
+			Pattern: gitignore.ParsePattern(pattern, nil),
+			Source:  source,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,174 @@
+package pathspec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func split(relPath string) []string {
+	return strings.Split(relPath, "/")
+}
+
+func TestChainLastMatchWins(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		path    string
+		isDir   bool
+		exclude bool
+	}{
+		{
+			name:    "no rules matches nothing",
+			rules:   nil,
+			path:    "foo.txt",
+			exclude: false,
+		},
+		{
+			name:    "single exclude",
+			rules:   CompileIgnorePatterns([]string{"*.log"}, false, "test"),
+			path:    "debug.log",
+			exclude: true,
+		},
+		{
+			name:    "later re-include overrides earlier exclude",
+			rules:   CompileIgnorePatterns([]string{"*.log", "!keep.log"}, false, "test"),
+			path:    "keep.log",
+			exclude: false,
+		},
+		{
+			name:    "later exclude overrides earlier re-include",
+			rules:   CompileIgnorePatterns([]string{"!keep.log", "*.log"}, false, "test"),
+			path:    "keep.log",
+			exclude: true,
+		},
+		{
+			name:    "non-matching path is untouched",
+			rules:   CompileIgnorePatterns([]string{"*.log"}, false, "test"),
+			path:    "keep.txt",
+			exclude: false,
+		},
+		{
+			name:    "filesOnly rule never excludes a directory",
+			rules:   CompileIgnorePatterns([]string{"build"}, true, "test"),
+			path:    "build",
+			isDir:   true,
+			exclude: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := New(tt.rules)
+			got := chain.Match(split(tt.path), tt.isDir)
+			if got != tt.exclude {
+				t.Errorf("Match(%q, isDir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.exclude)
+			}
+		})
+	}
+}
+
+func TestExtensionRule(t *testing.T) {
+	rule := ExtensionRule([]string{"go", ".txt"}, "--ext")
+	chain := New([]Rule{rule})
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		exclude bool
+	}{
+		{path: "main.go", exclude: false},
+		{path: "notes.txt", exclude: false},
+		{path: "image.png", exclude: true},
+		{path: "vendor", isDir: true, exclude: false}, // directories are never pruned by extension
+	}
+
+	for _, tt := range tests {
+		got := chain.Match(split(tt.path), tt.isDir)
+		if got != tt.exclude {
+			t.Errorf("Match(%q, isDir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.exclude)
+		}
+	}
+}
+
+func TestHiddenRule(t *testing.T) {
+	chain := New([]Rule{HiddenRule("hidden file")})
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		exclude bool
+	}{
+		{path: ".env", exclude: true},
+		{path: "sub/.env", exclude: true},
+		{path: ".git", isDir: true, exclude: true},
+		{path: "normal.txt", exclude: false},
+	}
+
+	for _, tt := range tests {
+		got := chain.Match(split(tt.path), tt.isDir)
+		if got != tt.exclude {
+			t.Errorf("Match(%q, isDir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.exclude)
+		}
+	}
+}
+
+func TestFromGitignoreLinesNegation(t *testing.T) {
+	rules := FromGitignoreLines([]string{"*.log", "!keep.log"}, nil, "test/.gitignore")
+	chain := New(rules)
+
+	if !chain.Match(split("drop.log"), false) {
+		t.Error("drop.log should be excluded")
+	}
+	if chain.Match(split("keep.log"), false) {
+		t.Error("keep.log should be re-included")
+	}
+}
+
+func TestLoadRulesFileIncludeCycleDetection(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.rules")
+	b := filepath.Join(dir, "b.rules")
+
+	if err := os.WriteFile(a, []byte("#include b.rules\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("#include a.rules\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadRulesFile(a)
+	if err == nil {
+		t.Fatal("expected an error for a circular #include, got nil")
+	}
+	if !strings.Contains(err.Error(), "circular") {
+		t.Errorf("error %q doesn't mention the cycle", err)
+	}
+}
+
+func TestLoadRulesFileInclude(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared.rules")
+	main := filepath.Join(dir, "main.rules")
+
+	if err := os.WriteFile(shared, []byte("*.log\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(main, []byte("#include shared.rules\n!keep.log\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRulesFile(main)
+	if err != nil {
+		t.Fatalf("LoadRulesFile: %v", err)
+	}
+
+	chain := New(rules)
+	if chain.Match(split("keep.log"), false) {
+		t.Error("keep.log should be re-included by the including file's own rule")
+	}
+	if !chain.Match(split("other.log"), false) {
+		t.Error("other.log should still be excluded by the included file's rule")
+	}
+}
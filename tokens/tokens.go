@@ -0,0 +1,69 @@
+// Package tokens estimates how many LLM tokens a chunk of text will cost,
+// so callers can pack files into a fixed context budget. None of the
+// estimators here run a real BPE encoder (vendoring cl100k/o200k's encoder
+// tables is a lot of weight for a file-listing tool) — they're deliberately
+// cheap approximations, good enough to budget against, not to bill against.
+package tokens
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+)
+
+// Tokenizer estimates the token count of a piece of text.
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+var wordPattern = regexp.MustCompile(`\S+`)
+
+// wordRatioTokenizer approximates a BPE tokenizer by counting whitespace-
+// separated words and scaling by an empirically-observed tokens-per-word
+// ratio for English-ish source code and prose.
+type wordRatioTokenizer struct {
+	name          string
+	tokensPerWord float64
+}
+
+func (w wordRatioTokenizer) Name() string { return w.name }
+
+func (w wordRatioTokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	words := wordPattern.FindAllString(text, -1)
+	return int(math.Ceil(float64(len(words)) * w.tokensPerWord))
+}
+
+// byteRatioTokenizer estimates token count as a fixed number of bytes per
+// token, with no dependency on word boundaries or language.
+type byteRatioTokenizer struct {
+	name          string
+	bytesPerToken float64
+}
+
+func (b byteRatioTokenizer) Name() string { return b.name }
+
+func (b byteRatioTokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(math.Ceil(float64(len(text)) / b.bytesPerToken))
+}
+
+// ByName resolves a tokenizer by its CLI/config name: "cl100k", "o200k", or
+// "bytes/4".
+func ByName(name string) (Tokenizer, error) {
+	switch name {
+	case "cl100k":
+		return wordRatioTokenizer{name: "cl100k", tokensPerWord: 1.3}, nil
+	case "o200k":
+		return wordRatioTokenizer{name: "o200k", tokensPerWord: 1.25}, nil
+	case "bytes/4":
+		return byteRatioTokenizer{name: "bytes/4", bytesPerToken: 4}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q", name)
+	}
+}